@@ -21,25 +21,46 @@ func main() {
 			Type: reflect.TypeFor[model.Category](),
 			Implementations: []any{
 				data.Crud{
-					Operations: data.OperationsCrud,
+					Operations: gen.NewFrozenSet(
+						data.OperationFindByKey, data.OperationList, data.OperationCreate, data.OperationUpdate,
+						data.OperationDelete, data.OperationUpsert, data.OperationPatch, data.OperationBatch,
+					),
 				},
 				data.FilterKeys{},
 				data.ListByAssociatedKey{
 					AssociatedType: reflect.TypeFor[model.Product](),
 					Reversed:       true,
 				},
+				data.ListByAssociatedKey{
+					AssociatedType: reflect.TypeFor[model.Listing](),
+					Reversed:       true,
+				},
+				data.SoftDelete{},
+				data.Audited{},
+				data.History{},
+				gorm.ChangeTriggers{
+					Version: 12,
+				},
 			},
 		},
 		{
 			Type: reflect.TypeFor[model.Product](),
 			Implementations: []any{
 				data.Crud{
-					Operations: data.OperationsCrud,
+					Operations: data.OperationsCrudBatch,
 				},
 				data.Search{},
+				data.Audited{},
+				data.Outbox{},
+				gorm.ChangeTriggers{
+					Version: 13,
+				},
 				data.Associate{
 					ChildType: reflect.TypeFor[model.Category](),
 				},
+				data.Associate{
+					ChildType: reflect.TypeFor[model.Listing](),
+				},
 				data.ListByAssociatedKey{
 					AssociatedType: reflect.TypeFor[model.Category](),
 				},
@@ -55,6 +76,22 @@ func main() {
 							Label:     "Query",
 							Operators: []data2.SearchOperator{data2.SearchOperatorStringMatch},
 						},
+						{
+							Name:  "sku",
+							Label: "SKU",
+							Operators: []data2.SearchOperator{
+								data2.SearchOperatorStringEquals,
+								data2.SearchOperatorStringNotEquals,
+								data2.SearchOperatorStringIn,
+								data2.SearchOperatorStringNotIn,
+							},
+						},
+						{
+							Name:      "description",
+							Label:     "Description",
+							Operators: []data2.SearchOperator{data2.SearchOperatorStringEquals, data2.SearchOperatorStringMatch},
+							Column:    "products.description",
+						},
 					},
 				},
 			},
@@ -63,11 +100,31 @@ func main() {
 			Type: reflect.TypeFor[model.Theme](),
 			Implementations: []any{
 				data.Crud{
-					Operations: data.OperationsCrud,
+					Operations: gen.NewFrozenSet(
+						data.OperationFindByKey, data.OperationList, data.OperationCreate, data.OperationUpdate,
+						data.OperationDelete, data.OperationUpsert, data.OperationBatch,
+					),
 				},
+				data.History{},
 				gorm.Implementation{
 					TableName:        "themes2",
 					ContextScopeCode: jen.Id("WithTenantScope").Call(),
+					ConflictColumns:  []string{"tenant_id", "name"},
+				},
+			},
+		},
+		{
+			Type: reflect.TypeFor[model.Listing](),
+			Implementations: []any{
+				data.Crud{
+					Operations: data.OperationsCrudBatch,
+				},
+				data.FilterKeys{},
+				data.Associate{
+					ChildType: reflect.TypeFor[model.Category](),
+				},
+				data.ListByAssociatedKey{
+					AssociatedType: reflect.TypeFor[model.Category](),
 				},
 			},
 		},
@@ -103,4 +160,9 @@ func main() {
 		IndexModule:     "example.data.gorm",
 	})
 
+	gorm.NewDataRegistry().RunDirectoryPathHandler("../repository/gorm/migrations", &gorm.ChangeTriggersMain{
+		Version: 11,
+		Entries: ds,
+	})
+
 }
@@ -1,9 +1,13 @@
 package model
 
-// Category represents a categorization entity with a unique name and description.
+import "github.com/activatedio/datainfra/pkg/data"
+
+// Category represents a categorization entity with a unique name, description and labels.
 type Category struct {
 	Name        string `data:"key" gorm:"primaryKey"`
 	Description string
+	Version     int `data:"version"`
+	Labels      data.Labels
 }
 
 // GetKey returns the name of the Category instance.
@@ -11,10 +15,15 @@ func (c *Category) GetKey() string {
 	return c.Name
 }
 
+// GetLabels returns the labels of the Category instance.
+func (c *Category) GetLabels() data.Labels {
+	return c.Labels
+}
+
 // Product represents an item with a Stock Keeping Unit (SKU) and a description.
 type Product struct {
 	SKU         string `data:"key" gorm:"primaryKey"`
-	Description string
+	Description string `data:"sortable"`
 }
 
 // GetStringID returns the SKU value of the Product instance.
@@ -32,3 +41,10 @@ type Theme struct {
 func (t *Theme) GetStringID() string {
 	return t.Name
 }
+
+// Listing represents a product offered in a market, identified by the market and SKU together.
+type Listing struct {
+	Market      string `data:"key" gorm:"primaryKey"`
+	SKU         string `data:"key" gorm:"primaryKey"`
+	Description string
+}
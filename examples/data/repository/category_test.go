@@ -1,11 +1,15 @@
 package repository_test
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/activatedio/datainfra/examples/data/model"
 	"github.com/activatedio/datainfra/examples/data/repository"
 	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
 	datatesting "github.com/activatedio/datainfra/pkg/data/testing"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -19,6 +23,18 @@ func TestCategoryRepository_Crud(t *testing.T) {
 			&datatesting.CrudTestFixture[*model.Category, string]{
 				KeyExists:  "a",
 				KeyMissing: "invalid",
+				SelectAssertions: []datatesting.SelectAssertion{
+					{Expression: "env=prod", ExpectedCount: 1},
+					{Expression: "env in (prod,dev)", ExpectedCount: 2},
+					{Expression: "env=prod,tier=1", ExpectedCount: 1},
+					{Expression: "env=dev,tier", ExpectedCount: 0},
+					{Expression: "env!=prod,env", ExpectedCount: 1},
+					{Expression: "env notin (prod),env", ExpectedCount: 1},
+					{Expression: "tier", ExpectedCount: 1},
+					{Expression: "env,!tier", ExpectedCount: 1},
+					// Applied in memory
+					{Expression: "tier>0", ExpectedCount: 1},
+				},
 				NewEntity: func() *model.Category {
 					return &model.Category{}
 				},
@@ -32,9 +48,11 @@ func TestCategoryRepository_Crud(t *testing.T) {
 				ModifyBeforeCreate: func(e *model.Category) {
 					e.Name = uuid.New().String()
 					e.Description = "initial"
+					e.Labels = datatesting.RandomLabels()
 				},
 				AssertAfterCreate: func(_ *testing.T, e *model.Category) {
 					a.Equal("initial", e.Description)
+					a.Len(e.Labels, 2)
 				},
 				ModifyBeforeUpdate: func(e *model.Category) {
 					e.Description = "modified"
@@ -69,3 +87,454 @@ func TestCategoryRepository_ListByProduct(t *testing.T) {
 
 	})
 }
+
+func TestCategoryRepository_UpdateStaleVersion(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.CategoryRepository) {
+
+		ctx := cp.GetContext()
+		name := uuid.New().String()
+
+		r.NoError(unit.Create(ctx, &model.Category{Name: name, Description: "initial"}))
+
+		first, err := unit.FindByKey(ctx, name)
+		r.NoError(err)
+		second, err := unit.FindByKey(ctx, name)
+		r.NoError(err)
+
+		first.Description = "first"
+		r.NoError(unit.Update(ctx, first))
+		a.Equal(1, first.Version)
+
+		second.Description = "second"
+		err = unit.Update(ctx, second)
+		a.ErrorIs(err, data.ErrStaleVersion)
+		a.ErrorIs(err, data.ErrConflict)
+		// Version is not incremented on failure
+		a.Equal(0, second.Version)
+
+		got, err := unit.FindByKey(ctx, name)
+		r.NoError(err)
+		a.Equal("first", got.Description)
+		a.Equal(1, got.Version)
+
+		got.Description = "third"
+		r.NoError(unit.Update(ctx, got))
+		a.Equal(2, got.Version)
+
+		r.NoError(unit.Delete(ctx, name))
+		a.ErrorIs(unit.Update(ctx, got), data.ErrNotFound)
+	})
+}
+
+func TestCategoryRepository_SoftDelete(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.CategoryRepository) {
+
+		ctx := cp.GetContext()
+		name := uuid.New().String()
+
+		listed := func(ctx context.Context) bool {
+			got, err := unit.ListAll(ctx, data.ListParams{})
+			r.NoError(err)
+			for _, c := range got.List {
+				if c.Name == name {
+					return true
+				}
+			}
+			return false
+		}
+
+		r.NoError(unit.Create(ctx, &model.Category{Name: name, Description: "initial"}))
+		r.NoError(unit.Delete(ctx, name))
+
+		got, err := unit.FindByKey(ctx, name)
+		a.ErrorIs(err, data.ErrNotFound)
+		a.Nil(got)
+		a.False(listed(ctx))
+
+		keys, err := unit.FilterKeys(ctx, []string{name})
+		r.NoError(err)
+		a.Empty(keys)
+
+		// Deleted entities are not updated
+		a.ErrorIs(unit.Update(ctx, &model.Category{Name: name, Description: "modified"}), data.ErrNotFound)
+
+		got, err = unit.FindByKey(data.WithDeleted(ctx), name)
+		r.NoError(err)
+		if a.NotNil(got) {
+			a.Equal("initial", got.Description)
+		}
+		a.True(listed(data.WithDeleted(ctx)))
+
+		r.NoError(unit.Restore(ctx, name))
+		a.ErrorIs(unit.Restore(ctx, name), data.ErrNotFound)
+
+		got, err = unit.FindByKey(ctx, name)
+		r.NoError(err)
+		a.NotNil(got)
+		a.True(listed(ctx))
+
+		r.NoError(unit.DeleteEntity(ctx, got))
+
+		got, err = unit.FindByKey(ctx, name)
+		a.ErrorIs(err, data.ErrNotFound)
+		a.Nil(got)
+
+		r.NoError(unit.Purge(ctx, name))
+
+		got, err = unit.FindByKey(data.WithDeleted(ctx), name)
+		a.ErrorIs(err, data.ErrNotFound)
+		a.Nil(got)
+		a.ErrorIs(unit.Restore(ctx, name), data.ErrNotFound)
+	})
+}
+
+func TestCategoryRepository_CreateManySoftDeleted(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.CategoryRepository) {
+
+		ctx := cp.GetContext()
+		deleted, created := uuid.New().String(), uuid.New().String()
+
+		r.NoError(unit.Create(ctx, &model.Category{Name: deleted, Description: "deleted"}))
+		r.NoError(unit.Delete(ctx, deleted))
+
+		// The soft deleted entity still holds its key, as with Create, while the others of the chunk are created
+		results, err := unit.CreateMany(ctx, []*model.Category{
+			{Name: deleted, Description: "again"},
+			{Name: created, Description: "created"},
+		})
+		r.NoError(err)
+		r.Len(results, 2)
+		a.ErrorIs(results[0], data.ErrAlreadyExists)
+		a.NoError(results[1])
+
+		got, err := unit.FindByKeys(data.WithDeleted(ctx), []string{deleted, created})
+		r.NoError(err)
+		r.Len(got, 2)
+		if a.NotNil(got[0]) {
+			a.Equal("deleted", got[0].Description)
+		}
+		if a.NotNil(got[1]) {
+			a.Equal("created", got[1].Description)
+		}
+
+		r.NoError(unit.Purge(ctx, deleted))
+		r.NoError(unit.Purge(ctx, created))
+	})
+}
+
+func TestCategoryRepository_Upsert(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.CategoryRepository) {
+
+		ctx := cp.GetContext()
+		name := uuid.New().String()
+
+		inserted, err := unit.Upsert(ctx, &model.Category{Name: name, Description: "initial"})
+		r.NoError(err)
+		a.True(inserted)
+
+		inserted, err = unit.Upsert(ctx, &model.Category{Name: name, Description: "modified"})
+		r.NoError(err)
+		a.False(inserted)
+
+		got, err := unit.FindByKey(ctx, name)
+		r.NoError(err)
+		if a.NotNil(got) {
+			a.Equal("modified", got.Description)
+			// The stored version is incremented on update
+			a.Equal(1, got.Version)
+		}
+
+		// A soft deleted entity is restored
+		r.NoError(unit.Delete(ctx, name))
+
+		inserted, err = unit.Upsert(ctx, &model.Category{Name: name, Description: "restored"})
+		r.NoError(err)
+		a.False(inserted)
+
+		got, err = unit.FindByKey(ctx, name)
+		r.NoError(err)
+		if a.NotNil(got) {
+			a.Equal("restored", got.Description)
+			a.Equal(2, got.Version)
+		}
+
+		r.NoError(unit.Purge(ctx, name))
+	})
+}
+
+func TestCategoryRepository_Patch(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.CategoryRepository) {
+
+		ctx := cp.GetContext()
+		name := uuid.New().String()
+
+		r.NoError(unit.Create(ctx, &model.Category{
+			Name:        name,
+			Description: "initial",
+			Labels:      data.Labels{"env": "prod"},
+		}))
+
+		// Only the fields in the mask are updated
+		r.NoError(unit.Patch(ctx, name, []string{"description"}, &model.Category{Description: "modified"}))
+
+		got, err := unit.FindByKey(ctx, name)
+		r.NoError(err)
+		a.Equal("modified", got.Description)
+		a.Equal(data.Labels{"env": "prod"}, got.Labels)
+		a.Equal(1, got.Version)
+
+		// Fields may be named by their field name
+		r.NoError(unit.Patch(ctx, name, []string{"Labels"}, &model.Category{Labels: data.Labels{"env": "dev"}}))
+
+		got, err = unit.FindByKey(ctx, name)
+		r.NoError(err)
+		a.Equal("modified", got.Description)
+		a.Equal(data.Labels{"env": "dev"}, got.Labels)
+		a.Equal(2, got.Version)
+
+		// With the version in the mask, the version must match
+		a.ErrorIs(unit.Patch(ctx, name, []string{"description", "version"},
+			&model.Category{Description: "stale", Version: 1}), data.ErrStaleVersion)
+		versioned := &model.Category{Description: "current", Version: 2}
+		r.NoError(unit.Patch(ctx, name, []string{"description", "version"}, versioned))
+		a.Equal(3, versioned.Version)
+
+		got, err = unit.FindByKey(ctx, name)
+		r.NoError(err)
+		a.Equal("current", got.Description)
+		a.Equal(3, got.Version)
+
+		// The new version is written back, so the patched entity may then be updated
+		patched := &model.Category{Name: name, Description: "patched"}
+		r.NoError(unit.Patch(ctx, name, []string{"description"}, patched))
+		a.Equal(4, patched.Version)
+
+		patched.Description = "updated"
+		r.NoError(unit.Update(ctx, patched))
+		a.Equal(5, patched.Version)
+
+		got, err = unit.FindByKey(ctx, name)
+		r.NoError(err)
+		a.Equal("updated", got.Description)
+		a.Equal(5, got.Version)
+
+		for _, mask := range [][]string{nil, {"invalid"}, {"name"}, {"deleted_at"}} {
+			a.ErrorIs(unit.Patch(ctx, name, mask, &model.Category{}), data.ErrInvalidFieldMask)
+		}
+
+		a.ErrorIs(unit.Patch(ctx, uuid.New().String(), []string{"description"}, &model.Category{}), data.ErrNotFound)
+
+		// Soft deleted entities are not patched
+		r.NoError(unit.Delete(ctx, name))
+		a.ErrorIs(unit.Patch(ctx, name, []string{"description"}, &model.Category{}), data.ErrNotFound)
+
+		r.NoError(unit.Purge(ctx, name))
+	})
+}
+
+// auditRecord is the audit columns of a row.
+type auditRecord struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	CreatedBy string
+	UpdatedBy string
+}
+
+func getAuditRecord(ctx context.Context, r *require.Assertions, table string, where string, args ...any) auditRecord {
+	var got auditRecord
+	r.NoError(gorm.GetDB(data.WithDeleted(ctx)).Table(table).Select("created_at, updated_at, created_by, updated_by").
+		Where(where, args...).Take(&got).Error)
+	return got
+}
+
+func TestCategoryRepository_Audit(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.CategoryRepository) {
+
+		ctx := cp.GetContext()
+		name := uuid.New().String()
+
+		r.NoError(unit.Create(data.WithActor(ctx, "creator"), &model.Category{Name: name, Description: "initial"}))
+
+		created := getAuditRecord(ctx, r, "categories", "name = ?", name)
+		a.Equal("creator", created.CreatedBy)
+		a.Equal("creator", created.UpdatedBy)
+		a.False(created.CreatedAt.IsZero())
+		a.Equal(created.CreatedAt, created.UpdatedAt)
+
+		time.Sleep(10 * time.Millisecond)
+
+		// The created columns are left unchanged by updates
+		r.NoError(unit.Update(data.WithActor(ctx, "updater"), &model.Category{Name: name, Description: "modified"}))
+
+		got := getAuditRecord(ctx, r, "categories", "name = ?", name)
+		a.Equal("creator", got.CreatedBy)
+		a.Equal("updater", got.UpdatedBy)
+		a.True(created.CreatedAt.Equal(got.CreatedAt))
+		a.True(got.UpdatedAt.After(created.UpdatedAt))
+
+		r.NoError(unit.Patch(data.WithActor(ctx, "patcher"), name, []string{"description"}, &model.Category{Description: "patched"}))
+
+		got = getAuditRecord(ctx, r, "categories", "name = ?", name)
+		a.Equal("creator", got.CreatedBy)
+		a.Equal("patcher", got.UpdatedBy)
+
+		a.ErrorIs(unit.Patch(ctx, name, []string{"created_by"}, &model.Category{}), data.ErrInvalidFieldMask)
+
+		_, err := unit.Upsert(data.WithActor(ctx, "upserter"), &model.Category{Name: name, Description: "upserted"})
+		r.NoError(err)
+
+		got = getAuditRecord(ctx, r, "categories", "name = ?", name)
+		a.Equal("creator", got.CreatedBy)
+		a.Equal("upserter", got.UpdatedBy)
+		a.True(created.CreatedAt.Equal(got.CreatedAt))
+
+		r.NoError(unit.Purge(ctx, name))
+	})
+}
+
+func TestCategoryRepository_History(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.CategoryRepository) {
+
+		ctx := cp.GetContext()
+		name := uuid.New().String()
+
+		r.NoError(unit.Create(data.WithActor(ctx, "creator"), &model.Category{Name: name, Description: "initial"}))
+		r.NoError(unit.Update(data.WithActor(ctx, "updater"), &model.Category{Name: name, Description: "modified"}))
+		r.NoError(unit.Patch(ctx, name, []string{"description"}, &model.Category{Description: "patched"}))
+
+		// Failed changes are not recorded
+		a.ErrorIs(unit.Create(ctx, &model.Category{Name: name, Description: "duplicate"}), data.ErrAlreadyExists)
+		a.ErrorIs(unit.Update(ctx, &model.Category{Name: name, Description: "stale", Version: 1}), data.ErrStaleVersion)
+
+		r.NoError(unit.Delete(data.WithActor(ctx, "deleter"), name))
+
+		got, err := unit.ListHistory(ctx, name, data.ListParams{})
+		r.NoError(err)
+		r.Len(got.List, 4)
+		a.Empty(got.NextPageToken)
+
+		descriptionOf := func(snapshot json.RawMessage) string {
+			var v struct {
+				Description string
+			}
+			r.NoError(json.Unmarshal(snapshot, &v))
+			return v.Description
+		}
+
+		create, update, patch, del := got.List[0], got.List[1], got.List[2], got.List[3]
+
+		a.Equal(data.HistoryOperationCreate, create.Operation)
+		a.Equal("creator", create.Actor)
+		a.False(create.ChangedAt.IsZero())
+		a.Nil(create.Before)
+		a.Equal("initial", descriptionOf(create.After))
+
+		a.Equal(data.HistoryOperationUpdate, update.Operation)
+		a.Equal("updater", update.Actor)
+		a.Equal("initial", descriptionOf(update.Before))
+		a.Equal("modified", descriptionOf(update.After))
+
+		a.Equal(data.HistoryOperationUpdate, patch.Operation)
+		a.Empty(patch.Actor)
+		a.Equal("modified", descriptionOf(patch.Before))
+		a.Equal("patched", descriptionOf(patch.After))
+
+		a.Equal(data.HistoryOperationDelete, del.Operation)
+		a.Equal("deleter", del.Actor)
+		a.Equal("patched", descriptionOf(del.Before))
+		a.Nil(del.After)
+
+		// Deleting again finds nothing and is not recorded
+		a.ErrorIs(unit.Delete(ctx, name), data.ErrNotFound)
+
+		// Paging, newest first
+		page, err := unit.ListHistory(ctx, name, data.ListParams{
+			Sort:       []data.SortCriterion{{Field: "id", Direction: data.SortDirectionDescending}},
+			PageParams: &data.PageParams{Count: 3},
+		})
+		r.NoError(err)
+		r.Len(page.List, 3)
+		a.Equal(data.HistoryOperationDelete, page.List[0].Operation)
+		r.NotEmpty(page.NextPageToken)
+
+		page, err = unit.ListHistory(ctx, name, data.ListParams{
+			Sort:       []data.SortCriterion{{Field: "id", Direction: data.SortDirectionDescending}},
+			PageParams: &data.PageParams{Count: 3, PageToken: page.NextPageToken},
+		})
+		r.NoError(err)
+		r.Len(page.List, 1)
+		a.Equal(data.HistoryOperationCreate, page.List[0].Operation)
+		a.Empty(page.NextPageToken)
+
+		_, err = unit.ListHistory(ctx, name, data.ListParams{Sort: []data.SortCriterion{{Field: "changed_at"}}})
+		a.Error(err)
+
+		got, err = unit.ListHistory(ctx, uuid.New().String(), data.ListParams{})
+		r.NoError(err)
+		a.Empty(got.List)
+
+		r.NoError(unit.Purge(ctx, name))
+	})
+}
+
+func TestCategoryRepository_HistoryRestorePurge(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.CategoryRepository) {
+
+		ctx := cp.GetContext()
+		name := uuid.New().String()
+
+		r.NoError(unit.Create(ctx, &model.Category{Name: name, Description: "initial"}))
+		r.NoError(unit.Delete(ctx, name))
+		r.NoError(unit.Restore(data.WithActor(ctx, "restorer"), name))
+
+		// A failed restore is not recorded
+		a.ErrorIs(unit.Restore(ctx, name), data.ErrNotFound)
+
+		r.NoError(unit.Purge(data.WithActor(ctx, "purger"), name))
+
+		got, err := unit.ListHistory(ctx, name, data.ListParams{})
+		r.NoError(err)
+		r.Len(got.List, 4)
+
+		restore, purge := got.List[2], got.List[3]
+
+		a.Equal(data.HistoryOperationRestore, restore.Operation)
+		a.Equal("restorer", restore.Actor)
+		a.Nil(restore.Before)
+		a.NotNil(restore.After)
+
+		a.Equal(data.HistoryOperationDelete, purge.Operation)
+		a.Equal("purger", purge.Actor)
+		a.JSONEq(string(restore.After), string(purge.Before))
+		a.Nil(purge.After)
+
+		// The purge of a soft deleted entity records nothing, since its delete was recorded
+		name = uuid.New().String()
+
+		r.NoError(unit.Create(ctx, &model.Category{Name: name, Description: "initial"}))
+		r.NoError(unit.Delete(ctx, name))
+		r.NoError(unit.Purge(ctx, name))
+
+		got, err = unit.ListHistory(ctx, name, data.ListParams{})
+		r.NoError(err)
+		r.Len(got.List, 2)
+		a.Equal(data.HistoryOperationCreate, got.List[0].Operation)
+		a.Equal(data.HistoryOperationDelete, got.List[1].Operation)
+	})
+}
@@ -0,0 +1,149 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/activatedio/datainfra/examples/data/model"
+	"github.com/activatedio/datainfra/examples/data/repository"
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
+	datatesting "github.com/activatedio/datainfra/pkg/data/testing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gorm2 "gorm.io/gorm"
+)
+
+func TestChangeListener_Subscribe(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit gorm.ChangeListener,
+		cr repository.CategoryRepository, pr repository.ProductRepository) {
+
+		ctx := cp.GetContext()
+
+		listenCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		changes, err := unit.Subscribe(listenCtx)
+		r.NoError(err)
+
+		name := uuid.New().String()
+		sku := uuid.New().String()
+		rolledBack := uuid.New().String()
+		last := uuid.New().String()
+
+		r.NoError(cr.Create(ctx, &model.Category{Name: name, Description: name}))
+		r.NoError(pr.Create(ctx, &model.Product{SKU: sku, Description: sku}))
+		// Soft deleted, then removed
+		r.NoError(cr.Delete(ctx, name))
+		r.NoError(cr.Purge(ctx, name))
+
+		r.Error(gorm.InTx(ctx, func(ctx context.Context) error {
+			r.NoError(pr.Create(ctx, &model.Product{SKU: rolledBack, Description: rolledBack}))
+			return errors.New("rollback")
+		}))
+
+		r.NoError(pr.Create(ctx, &model.Product{SKU: last, Description: last}))
+
+		var got []*data.Change
+
+	collect:
+		for {
+			select {
+			case c, ok := <-changes:
+				r.True(ok)
+				if c.Key["sku"] == last {
+					break collect
+				}
+				if c.Key["name"] == name || c.Key["sku"] == sku || c.Key["sku"] == rolledBack {
+					got = append(got, c)
+				}
+			case <-time.After(5 * time.Second):
+				r.Fail("timed out waiting for changes")
+			}
+		}
+
+		a.Equal([]*data.Change{
+			{Table: "categories", Key: map[string]any{"name": name}, Op: data.HistoryOperationCreate},
+			{Table: "products", Key: map[string]any{"sku": sku}, Op: data.HistoryOperationCreate},
+			{Table: "categories", Key: map[string]any{"name": name}, Op: data.HistoryOperationUpdate},
+			{Table: "categories", Key: map[string]any{"name": name}, Op: data.HistoryOperationDelete},
+		}, got)
+
+		// The channel is closed once the context is done
+		cancel()
+
+		select {
+		case _, ok := <-changes:
+			a.False(ok)
+		case <-time.After(5 * time.Second):
+			a.Fail("channel not closed")
+		}
+
+	}, gorm.NewChangeListener, func() *gorm.ChangeListenerConfig {
+		return &gorm.ChangeListenerConfig{PollInterval: 10 * time.Millisecond}
+	})
+}
+
+func TestChangeListener_SubscribeRetryAndPrune(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, config *gorm.Config, db *gorm2.DB,
+		unit gorm.ChangeListener, cr repository.CategoryRepository) {
+
+		// The change table is only polled on sqlite
+		if config.Dialect != gorm.DialectSqlite {
+			return
+		}
+
+		ctx := cp.GetContext()
+
+		r.NoError(db.Exec("INSERT INTO datainfra_changes (table_name, entity_key, operation, changed_at) " +
+			"VALUES ('categories', '{}', 'INSERT', '2000-01-01 00:00:00')").Error)
+
+		listenCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		changes, err := unit.Subscribe(listenCtx)
+		r.NoError(err)
+
+		// Polling fails while the table is missing, and resumes once it is back
+		r.NoError(db.Exec("ALTER TABLE datainfra_changes RENAME TO datainfra_changes_moved").Error)
+		time.Sleep(50 * time.Millisecond)
+		r.NoError(db.Exec("ALTER TABLE datainfra_changes_moved RENAME TO datainfra_changes").Error)
+
+		name := uuid.New().String()
+
+		r.NoError(cr.Create(ctx, &model.Category{Name: name, Description: name}))
+
+	collect:
+		for {
+			select {
+			case c, ok := <-changes:
+				r.True(ok)
+				if c.Key["name"] == name {
+					break collect
+				}
+			case <-time.After(5 * time.Second):
+				r.Fail("timed out waiting for changes")
+			}
+		}
+
+		var old int64
+		r.NoError(db.Table("datainfra_changes").Where("changed_at < ?", "2001-01-01").Count(&old).Error)
+		a.Zero(old)
+
+		r.NoError(cr.Purge(ctx, name))
+
+	}, gorm.NewChangeListener, func() *gorm.ChangeListenerConfig {
+		return &gorm.ChangeListenerConfig{
+			PollInterval:  10 * time.Millisecond,
+			RetryInterval: 10 * time.Millisecond,
+			PruneInterval: 10 * time.Millisecond,
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/activatedio/datainfra/examples/data/model"
+	"github.com/activatedio/datainfra/examples/data/repository"
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
+	datatesting "github.com/activatedio/datainfra/pkg/data/testing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateError(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ProductRepository) {
+
+		ctx := cp.GetContext()
+		db := gorm.GetDB(ctx)
+
+		insert := "INSERT INTO product_categories (product_sku, category_name, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)"
+
+		err := gorm.TranslateError(db.Exec(insert, "1", "a").Error)
+		a.ErrorIs(err, data.ErrAlreadyExists)
+
+		err = gorm.TranslateError(db.Exec(insert, uuid.New().String(), "a").Error)
+		a.ErrorIs(err, data.ErrForeignKeyViolation)
+
+		// Translated errors keep the cause
+		a.NotEqual(data.ForeignKeyViolation{}.Error(), err.Error())
+
+		a.NoError(gorm.TranslateError(nil))
+
+		sku := uuid.New().String()
+		r.NoError(unit.Create(ctx, &model.Product{SKU: sku, Description: sku}))
+
+		// Already associated
+		a.ErrorIs(unit.AssociateCategories(ctx, "1", []string{"a"}, nil), data.ErrAlreadyExists)
+		a.ErrorIs(unit.Update(ctx, &model.Product{SKU: uuid.New().String()}), data.ErrNotFound)
+	})
+}
@@ -2,6 +2,7 @@ package gorm
 
 import (
 	"context"
+	"time"
 
 	model "github.com/activatedio/datainfra/examples/data/model"
 	repository "github.com/activatedio/datainfra/examples/data/repository"
@@ -14,24 +15,40 @@ import (
 // CategoryInternal is the internal representation of Category
 type CategoryInternal struct {
 	*model.Category
+	DeletedAt *time.Time
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+	CreatedBy string    `gorm:"size:128"`
+	UpdatedBy string    `gorm:"size:128"`
 }
 
 // categoryRepositoryImpl is the implementation of CategoryRepository
 type categoryRepositoryImpl struct {
 	Template gorm.MappingTemplate[*model.Category, *CategoryInternal]
 	data.CrudTemplate[*model.Category, string]
+	data.BatchTemplate[*model.Category, string]
+	data.UpsertTemplate[*model.Category]
+	data.PatchTemplate[*model.Category, string]
 	data.FilterKeysTemplate[string]
+	data.SoftDeleteTemplate[string]
+	data.HistoryTemplate[string]
 }
 
 // CategoryRepositoryParams are the parameters for CategoryRepository
 type CategoryRepositoryParams struct {
 	fx.In
+	Instrumentation gorm.Instrumentation `optional:"true"`
 }
 
 // NewCategoryRepository creates a new CategoryRepository
-func NewCategoryRepository(CategoryRepositoryParams) repository.CategoryRepository {
+func NewCategoryRepository(params CategoryRepositoryParams) repository.CategoryRepository {
 	template := gorm.NewMappingTemplate[*model.Category, *CategoryInternal](gorm.MappingTemplateParams[*model.Category, *CategoryInternal]{
-		Table: "categories",
+		Table:            "categories",
+		KeyColumns:       []string{"name"},
+		LabelsColumn:     "labels",
+		SoftDeleteColumn: "deleted_at",
+		AuditColumns:     gorm.DefaultAuditColumns(),
+		Instrumentation:  params.Instrumentation,
 		ToInternal: func(m *model.Category) *CategoryInternal {
 			return &CategoryInternal{
 				Category: m,
@@ -41,16 +58,33 @@ func NewCategoryRepository(CategoryRepositoryParams) repository.CategoryReposito
 			return m.Category
 		},
 	})
+	crud := gorm.NewMappingCrudTemplates[*model.Category, *CategoryInternal, string](gorm.MappingCrudTemplateImplOptions[*model.Category, *CategoryInternal, string]{
+		Template:        template,
+		FindBuilder:     gorm.SingleFindBuilder[string]("categories.name"),
+		FindKeysBuilder: gorm.SingleFindKeysBuilder[string]("categories.name"),
+		VersionColumn:   "version",
+		HistoryTable:    "categories_history",
+	})
 	return &categoryRepositoryImpl{
-		Template: template,
-		CrudTemplate: gorm.NewMappingCrudTemplate[*model.Category, *CategoryInternal, string](gorm.MappingCrudTemplateImplOptions[*model.Category, *CategoryInternal, string]{
-			Template:    template,
-			FindBuilder: gorm.SingleFindBuilder[string]("categories.name"),
-		}),
+		Template:       template,
+		CrudTemplate:   crud,
+		BatchTemplate:  crud,
+		UpsertTemplate: crud,
+		PatchTemplate:  crud,
 		FilterKeysTemplate: gorm.NewMappingFilterKeysTemplate[*model.Category, *CategoryInternal, string](gorm.MappingFilterKeysTemplateImplOptions[*model.Category, *CategoryInternal, string]{
 			Template:   template,
 			FindColumn: "name",
 		}),
+		SoftDeleteTemplate: gorm.NewMappingSoftDeleteTemplate[*model.Category, *CategoryInternal, string](gorm.MappingSoftDeleteTemplateImplOptions[*model.Category, *CategoryInternal, string]{
+			Template:     template,
+			FindBuilder:  gorm.SingleFindBuilder[string]("categories.name"),
+			HistoryTable: "categories_history",
+		}),
+		HistoryTemplate: gorm.NewMappingHistoryTemplate[*model.Category, *CategoryInternal, string](gorm.MappingCrudTemplateImplOptions[*model.Category, *CategoryInternal, string]{
+			Template:     template,
+			FindBuilder:  gorm.SingleFindBuilder[string]("categories.name"),
+			HistoryTable: "categories_history",
+		}),
 	}
 }
 
@@ -59,3 +93,8 @@ func (r *categoryRepositoryImpl) ListByProduct(ctx context.Context, key string,
 		return tx.Joins("INNER JOIN product_categories ON product_categories.category_name = categories.name").Where("product_categories.product_sku=?", key)
 	}, params)
 }
+func (r *categoryRepositoryImpl) ListByListing(ctx context.Context, key repository.ListingKey, params data.ListParams) (*data.List[*model.Category], error) {
+	return r.Template.DoList(ctx, func(tx *gorm1.DB) *gorm1.DB {
+		return tx.Joins("INNER JOIN listing_categories ON listing_categories.category_name = categories.name").Where("listing_categories.listing_market=? AND listing_categories.listing_sku=?", key.Market, key.SKU)
+	}, params)
+}
@@ -7,5 +7,5 @@ import (
 
 // Index collects constructors for implementations in an fx module
 func Index() fx.Option {
-	return fx.Module("example.data.gorm", fx.Provide(gorm.NewDB, gorm.NewContextBuilder, NewCategoryRepository, NewProductRepository, NewThemeRepository))
+	return fx.Module("example.data.gorm", fx.Provide(gorm.NewDB, gorm.NewContextBuilder, gorm.NewTxManager, gorm.NewHealthChecker, NewCategoryRepository, NewProductRepository, NewThemeRepository, NewListingRepository))
 }
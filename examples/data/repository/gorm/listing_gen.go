@@ -0,0 +1,84 @@
+package gorm
+
+import (
+	"context"
+
+	model "github.com/activatedio/datainfra/examples/data/model"
+	repository "github.com/activatedio/datainfra/examples/data/repository"
+	data "github.com/activatedio/datainfra/pkg/data"
+	gorm "github.com/activatedio/datainfra/pkg/data/gorm"
+	fx "go.uber.org/fx"
+	gorm1 "gorm.io/gorm"
+)
+
+// ListingInternal is the internal representation of Listing
+type ListingInternal struct {
+	*model.Listing
+}
+
+// listingRepositoryImpl is the implementation of ListingRepository
+type listingRepositoryImpl struct {
+	Template gorm.MappingTemplate[*model.Listing, *ListingInternal]
+	data.CrudTemplate[*model.Listing, repository.ListingKey]
+	data.BatchTemplate[*model.Listing, repository.ListingKey]
+	categoryRepository repository.CategoryRepository
+	data.FilterKeysTemplate[repository.ListingKey]
+}
+
+// ListingRepositoryParams are the parameters for ListingRepository
+type ListingRepositoryParams struct {
+	fx.In
+	Instrumentation    gorm.Instrumentation `optional:"true"`
+	CategoryRepository repository.CategoryRepository
+}
+
+// NewListingRepository creates a new ListingRepository
+func NewListingRepository(params ListingRepositoryParams) repository.ListingRepository {
+	template := gorm.NewMappingTemplate[*model.Listing, *ListingInternal](gorm.MappingTemplateParams[*model.Listing, *ListingInternal]{
+		Table:           "listings",
+		KeyColumns:      []string{"market", "sku"},
+		Instrumentation: params.Instrumentation,
+		ToInternal: func(m *model.Listing) *ListingInternal {
+			return &ListingInternal{
+				Listing: m,
+			}
+		},
+		FromInternal: func(m *ListingInternal) *model.Listing {
+			return m.Listing
+		},
+	})
+	crud := gorm.NewMappingCrudTemplates[*model.Listing, *ListingInternal, repository.ListingKey](gorm.MappingCrudTemplateImplOptions[*model.Listing, *ListingInternal, repository.ListingKey]{
+		Template:        template,
+		FindBuilder:     gorm.CompositeFindBuilder[repository.ListingKey]("listings.market", "listings.sku"),
+		FindKeysBuilder: gorm.CompositeFindKeysBuilder[repository.ListingKey]("listings.market", "listings.sku"),
+	})
+	return &listingRepositoryImpl{
+		Template:           template,
+		CrudTemplate:       crud,
+		BatchTemplate:      crud,
+		categoryRepository: params.CategoryRepository,
+		FilterKeysTemplate: gorm.NewMappingFilterKeysTemplate[*model.Listing, *ListingInternal, repository.ListingKey](gorm.MappingFilterKeysTemplateImplOptions[*model.Listing, *ListingInternal, repository.ListingKey]{
+			Template:    template,
+			FindColumns: []string{"market", "sku"},
+		}),
+	}
+}
+
+func (r *listingRepositoryImpl) AssociateCategories(ctx context.Context, key repository.ListingKey, add []string, remove []string) error {
+	return gorm.Associate[repository.ListingKey, string](ctx, gorm.AssociateParams[repository.ListingKey, string]{
+		AssociationTable:  "listing_categories",
+		ParentColumnNames: []string{"listing_market", "listing_sku"},
+		ChildColumnName:   "category_name",
+		ParentKey:         key,
+		Add:               add,
+		Remove:            remove,
+		ParentRepository:  r,
+		ChildRepository:   r.categoryRepository,
+		Instrumentation:   r.Template.GetInstrumentation(),
+	})
+}
+func (r *listingRepositoryImpl) ListByCategory(ctx context.Context, key string, params data.ListParams) (*data.List[*model.Listing], error) {
+	return r.Template.DoList(ctx, func(tx *gorm1.DB) *gorm1.DB {
+		return tx.Joins("INNER JOIN listing_categories ON listing_categories.listing_market = listings.market AND listing_categories.listing_sku = listings.sku").Where("listing_categories.category_name=?", key)
+	}, params)
+}
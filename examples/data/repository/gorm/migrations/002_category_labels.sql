@@ -0,0 +1,7 @@
+-- +goose Up
+
+{{ if eq "postgres" .Dialect }}
+ALTER TABLE categories ADD COLUMN labels JSONB NOT NULL DEFAULT '{}';
+{{ else }}
+ALTER TABLE categories ADD COLUMN labels TEXT NOT NULL DEFAULT '{}';
+{{ end }}
@@ -0,0 +1,26 @@
+-- +goose Up
+
+{{ if eq "sqlite" .Dialect }}
+CREATE VIRTUAL TABLE products_fts USING fts5(description, content='products', content_rowid='rowid');
+
+INSERT INTO products_fts(products_fts) VALUES ('rebuild');
+
+-- +goose StatementBegin
+CREATE TRIGGER products_fts_insert AFTER INSERT ON products BEGIN
+  INSERT INTO products_fts(rowid, description) VALUES (new.rowid, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER products_fts_delete AFTER DELETE ON products BEGIN
+  INSERT INTO products_fts(products_fts, rowid, description) VALUES ('delete', old.rowid, old.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER products_fts_update AFTER UPDATE ON products BEGIN
+  INSERT INTO products_fts(products_fts, rowid, description) VALUES ('delete', old.rowid, old.description);
+  INSERT INTO products_fts(rowid, description) VALUES (new.rowid, new.description);
+END;
+-- +goose StatementEnd
+{{ end }}
@@ -0,0 +1,3 @@
+-- +goose Up
+
+ALTER TABLE categories ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
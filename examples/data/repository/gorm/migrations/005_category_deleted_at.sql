@@ -0,0 +1,3 @@
+-- +goose Up
+
+ALTER TABLE categories ADD COLUMN deleted_at TIMESTAMP NULL;
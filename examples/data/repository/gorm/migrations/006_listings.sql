@@ -0,0 +1,28 @@
+-- +goose Up
+
+CREATE TABLE listings (
+    market VARCHAR(64),
+    sku VARCHAR(64),
+    description VARCHAR(200),
+    PRIMARY KEY (market, sku)
+);
+
+CREATE TABLE listing_categories (
+    listing_market VARCHAR(64),
+    listing_sku VARCHAR(64),
+    category_name VARCHAR(64),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (listing_market, listing_sku, category_name),
+    FOREIGN KEY (listing_market, listing_sku) REFERENCES listings(market, sku),
+    FOREIGN KEY (category_name) REFERENCES categories(name)
+);
+
+CREATE TABLE product_listings (
+    product_sku VARCHAR(64),
+    listing_market VARCHAR(64),
+    listing_sku VARCHAR(64),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (product_sku, listing_market, listing_sku),
+    FOREIGN KEY (product_sku) REFERENCES products(sku),
+    FOREIGN KEY (listing_market, listing_sku) REFERENCES listings(market, sku)
+);
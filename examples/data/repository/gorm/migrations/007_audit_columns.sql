@@ -0,0 +1,21 @@
+-- +goose Up
+
+{{ if eq "postgres" .Dialect }}
+ALTER TABLE categories ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE categories ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE products ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE products ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+{{ else }}
+-- SQLite only adds columns with constant defaults, so existing rows are stamped after the columns are added
+ALTER TABLE categories ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE categories ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE products ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE products ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE categories SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
+UPDATE products SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
+{{ end }}
+
+ALTER TABLE categories ADD COLUMN created_by VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE categories ADD COLUMN updated_by VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN created_by VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN updated_by VARCHAR(128) NOT NULL DEFAULT '';
@@ -0,0 +1,23 @@
+-- +goose Up
+
+CREATE TABLE themes2_history (
+    {{ if eq "postgres" .Dialect }}
+    id BIGSERIAL PRIMARY KEY,
+    {{ else }}
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    {{ end }}
+    entity_key VARCHAR(256) NOT NULL,
+    scope VARCHAR(256) NOT NULL DEFAULT '',
+    operation VARCHAR(16) NOT NULL,
+    actor VARCHAR(128) NOT NULL DEFAULT '',
+    changed_at TIMESTAMP NOT NULL,
+    {{ if eq "postgres" .Dialect }}
+    before_data JSONB NULL,
+    after_data JSONB NULL
+    {{ else }}
+    before_data TEXT NULL,
+    after_data TEXT NULL
+    {{ end }}
+);
+
+CREATE INDEX themes2_history_entity_key ON themes2_history (entity_key, scope, id);
@@ -0,0 +1,19 @@
+-- +goose Up
+
+CREATE TABLE outbox (
+    {{ if eq "postgres" .Dialect }}
+    id BIGSERIAL PRIMARY KEY,
+    {{ else }}
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    {{ end }}
+    entity_type VARCHAR(64) NOT NULL,
+    entity_key VARCHAR(256) NOT NULL,
+    operation VARCHAR(16) NOT NULL,
+    {{ if eq "postgres" .Dialect }}
+    payload JSONB NULL,
+    {{ else }}
+    payload TEXT NULL,
+    {{ end }}
+    created_at TIMESTAMP NOT NULL,
+    claimed_at TIMESTAMP NULL
+);
@@ -2,6 +2,7 @@ package gorm
 
 import (
 	"context"
+	"time"
 
 	model "github.com/activatedio/datainfra/examples/data/model"
 	repository "github.com/activatedio/datainfra/examples/data/repository"
@@ -14,26 +15,38 @@ import (
 // ProductInternal is the internal representation of Product
 type ProductInternal struct {
 	*model.Product
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+	CreatedBy string    `gorm:"size:128"`
+	UpdatedBy string    `gorm:"size:128"`
 }
 
 // productRepositoryImpl is the implementation of ProductRepository
 type productRepositoryImpl struct {
 	Template gorm.MappingTemplate[*model.Product, *ProductInternal]
 	data.CrudTemplate[*model.Product, string]
+	data.BatchTemplate[*model.Product, string]
 	data.SearchTemplate[*model.Product]
 	categoryRepository repository.CategoryRepository
+	listingRepository  repository.ListingRepository
 }
 
 // ProductRepositoryParams are the parameters for ProductRepository
 type ProductRepositoryParams struct {
 	fx.In
+	Instrumentation    gorm.Instrumentation `optional:"true"`
 	CategoryRepository repository.CategoryRepository
+	ListingRepository  repository.ListingRepository
 }
 
 // NewProductRepository creates a new ProductRepository
 func NewProductRepository(params ProductRepositoryParams) repository.ProductRepository {
 	template := gorm.NewMappingTemplate[*model.Product, *ProductInternal](gorm.MappingTemplateParams[*model.Product, *ProductInternal]{
-		Table: "products",
+		Table:           "products",
+		KeyColumns:      []string{"sku"},
+		SortableFields:  []string{"description"},
+		AuditColumns:    gorm.DefaultAuditColumns(),
+		Instrumentation: params.Instrumentation,
 		ToInternal: func(m *model.Product) *ProductInternal {
 			return &ProductInternal{
 				Product: m,
@@ -43,12 +56,17 @@ func NewProductRepository(params ProductRepositoryParams) repository.ProductRepo
 			return m.Product
 		},
 	})
+	crud := gorm.NewMappingCrudTemplates[*model.Product, *ProductInternal, string](gorm.MappingCrudTemplateImplOptions[*model.Product, *ProductInternal, string]{
+		Template:        template,
+		FindBuilder:     gorm.SingleFindBuilder[string]("products.sku"),
+		FindKeysBuilder: gorm.SingleFindKeysBuilder[string]("products.sku"),
+		OutboxTable:     gorm.DefaultOutboxTable,
+		EntityType:      "Product",
+	})
 	return &productRepositoryImpl{
-		Template: template,
-		CrudTemplate: gorm.NewMappingCrudTemplate[*model.Product, *ProductInternal, string](gorm.MappingCrudTemplateImplOptions[*model.Product, *ProductInternal, string]{
-			Template:    template,
-			FindBuilder: gorm.SingleFindBuilder[string]("products.sku"),
-		}),
+		Template:      template,
+		CrudTemplate:  crud,
+		BatchTemplate: crud,
 		SearchTemplate: gorm.NewMappingSearchTemplate[*model.Product, *ProductInternal](gorm.MappingSearchTemplateParams[*model.Product, *ProductInternal]{
 			Template: template,
 			SearchPredicates: []*data.SearchPredicateDescriptor{
@@ -66,9 +84,29 @@ func NewProductRepository(params ProductRepositoryParams) repository.ProductRepo
 						data.SearchOperatorStringMatch,
 					},
 				},
+				{
+					Name:  "sku",
+					Label: "SKU",
+					Operators: []data.SearchOperator{
+						data.SearchOperatorStringEquals,
+						data.SearchOperatorStringNotEquals,
+						data.SearchOperatorStringIn,
+						data.SearchOperatorStringNotIn,
+					},
+				},
+				{
+					Name:  "description",
+					Label: "Description",
+					Operators: []data.SearchOperator{
+						data.SearchOperatorStringEquals,
+						data.SearchOperatorStringMatch,
+					},
+				},
 			},
+			PredicateColumns: map[string]string{"description": "products.description"},
 		}),
 		categoryRepository: params.CategoryRepository,
+		listingRepository:  params.ListingRepository,
 	}
 }
 
@@ -82,6 +120,20 @@ func (r *productRepositoryImpl) AssociateCategories(ctx context.Context, key str
 		Remove:           remove,
 		ParentRepository: r,
 		ChildRepository:  r.categoryRepository,
+		Instrumentation:  r.Template.GetInstrumentation(),
+	})
+}
+func (r *productRepositoryImpl) AssociateListings(ctx context.Context, key string, add []repository.ListingKey, remove []repository.ListingKey) error {
+	return gorm.Associate[string, repository.ListingKey](ctx, gorm.AssociateParams[string, repository.ListingKey]{
+		AssociationTable: "product_listings",
+		ParentColumnName: "product_sku",
+		ChildColumnNames: []string{"listing_market", "listing_sku"},
+		ParentKey:        key,
+		Add:              add,
+		Remove:           remove,
+		ParentRepository: r,
+		ChildRepository:  r.listingRepository,
+		Instrumentation:  r.Template.GetInstrumentation(),
 	})
 }
 func (r *productRepositoryImpl) ListByCategory(ctx context.Context, key string, params data.ListParams) (*data.List[*model.Product], error) {
@@ -2,6 +2,7 @@ package gorm
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/activatedio/datainfra/examples/data/model"
 	"github.com/activatedio/datainfra/pkg/data"
@@ -9,12 +10,13 @@ import (
 	gorm1 "gorm.io/gorm"
 )
 
-// WithTenantScope returns a ContextScopeFactory that applies a tenant filter to all queries.
+// WithTenantScope returns a ContextScopeFactory that applies a tenant filter to all queries. The tenant column is
+// qualified with the table, since the filter also conditions the updates of upserts.
 func WithTenantScope() gorm.ContextScopeFactory {
-	return func(ctx context.Context, _ string, _ data.FetchType) *gorm.ContextScope {
+	return func(ctx context.Context, table string, _ data.FetchType) *gorm.ContextScope {
 		return &gorm.ContextScope{
 			QueryModifier: func(db *gorm1.DB) *gorm1.DB {
-				return db.Where("tenant_id = ?", model.MustGetTenant(ctx))
+				return db.Where(fmt.Sprintf("%s.tenant_id = ?", table), model.MustGetTenant(ctx))
 			},
 			ValueInjector: func(e any) {
 				e.(model.TenantScoped).SetTenantID(model.MustGetTenant(ctx))
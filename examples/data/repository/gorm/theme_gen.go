@@ -23,18 +23,24 @@ func (r *ThemeInternal) SetTenantID(id string) {
 type themeRepositoryImpl struct {
 	Template gorm.MappingTemplate[*model.Theme, *ThemeInternal]
 	data.CrudTemplate[*model.Theme, string]
+	data.BatchTemplate[*model.Theme, string]
+	data.UpsertTemplate[*model.Theme]
+	data.HistoryTemplate[string]
 }
 
 // ThemeRepositoryParams are the parameters for ThemeRepository
 type ThemeRepositoryParams struct {
 	fx.In
+	Instrumentation gorm.Instrumentation `optional:"true"`
 }
 
 // NewThemeRepository creates a new ThemeRepository
-func NewThemeRepository(ThemeRepositoryParams) repository.ThemeRepository {
+func NewThemeRepository(params ThemeRepositoryParams) repository.ThemeRepository {
 	template := gorm.NewMappingTemplate[*model.Theme, *ThemeInternal](gorm.MappingTemplateParams[*model.Theme, *ThemeInternal]{
-		ContextScope: WithTenantScope(),
-		Table:        "themes2",
+		ContextScope:    WithTenantScope(),
+		Table:           "themes2",
+		KeyColumns:      []string{"name"},
+		Instrumentation: params.Instrumentation,
 		ToInternal: func(m *model.Theme) *ThemeInternal {
 			return &ThemeInternal{
 				Theme: m,
@@ -44,11 +50,22 @@ func NewThemeRepository(ThemeRepositoryParams) repository.ThemeRepository {
 			return m.Theme
 		},
 	})
+	crud := gorm.NewMappingCrudTemplates[*model.Theme, *ThemeInternal, string](gorm.MappingCrudTemplateImplOptions[*model.Theme, *ThemeInternal, string]{
+		Template:        template,
+		FindBuilder:     gorm.SingleFindBuilder[string]("themes2.name"),
+		FindKeysBuilder: gorm.SingleFindKeysBuilder[string]("themes2.name"),
+		ConflictColumns: []string{"tenant_id", "name"},
+		HistoryTable:    "themes2_history",
+	})
 	return &themeRepositoryImpl{
-		Template: template,
-		CrudTemplate: gorm.NewMappingCrudTemplate[*model.Theme, *ThemeInternal, string](gorm.MappingCrudTemplateImplOptions[*model.Theme, *ThemeInternal, string]{
-			Template:    template,
-			FindBuilder: gorm.SingleFindBuilder[string]("themes2.name"),
+		Template:       template,
+		CrudTemplate:   crud,
+		BatchTemplate:  crud,
+		UpsertTemplate: crud,
+		HistoryTemplate: gorm.NewMappingHistoryTemplate[*model.Theme, *ThemeInternal, string](gorm.MappingCrudTemplateImplOptions[*model.Theme, *ThemeInternal, string]{
+			Template:     template,
+			FindBuilder:  gorm.SingleFindBuilder[string]("themes2.name"),
+			HistoryTable: "themes2_history",
 		}),
 	}
 }
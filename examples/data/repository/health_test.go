@@ -0,0 +1,79 @@
+package repository_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/activatedio/datainfra/pkg/data/gorm"
+	datatesting "github.com/activatedio/datainfra/pkg/data/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx/fxtest"
+	gorm2 "gorm.io/gorm"
+)
+
+func TestHealthChecker(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, db *gorm2.DB, unit gorm.HealthChecker) {
+
+		ctx := cp.GetContext()
+
+		a.Nil(unit.Health())
+		a.ErrorIs(unit.Ready(), gorm.ErrNotReady)
+
+		got := unit.Check(ctx)
+		r.NoError(got.Err)
+		a.True(got.Ready())
+		a.Same(got, unit.Health())
+		a.NoError(unit.Ready())
+		a.Positive(got.PoolStats.OpenConnections)
+
+		r.Len(got.Migrations, 2)
+		for i, name := range []string{"main", "test"} {
+			m := got.Migrations[i]
+			a.Equal(name, m.Name)
+			a.Positive(m.ExpectedVersion)
+			a.Equal(m.ExpectedVersion, m.CurrentVersion)
+		}
+
+		migration := &fstest.MapFile{Data: []byte("-- +goose Up\nSELECT 1;\n")}
+
+		behind := gorm.NewHealthChecker(gorm.HealthCheckerParams{
+			Lifecycle: fxtest.NewLifecycle(t),
+			DB:        db,
+			Data: []gorm.MigratorData{
+				{
+					Name: "main",
+					FS: fstest.MapFS{
+						"migrations/001_initial.sql": migration,
+						"migrations/999_future.sql":  migration,
+					},
+					Path: "migrations",
+				},
+				{
+					Name: "missing",
+					FS: fstest.MapFS{
+						"001_initial.sql": migration,
+					},
+					Path: ".",
+				},
+			},
+		})
+
+		got = behind.Check(ctx)
+		a.False(got.Ready())
+		a.Error(behind.Ready())
+		a.ErrorContains(got.Err, "migrations main: at version")
+		a.ErrorContains(got.Err, "migrations missing:")
+
+		r.Len(got.Migrations, 2)
+		a.Equal(int64(999), got.Migrations[0].ExpectedVersion)
+		a.Positive(got.Migrations[0].CurrentVersion)
+		a.Equal(int64(-1), got.Migrations[1].CurrentVersion)
+
+		// Checks do not create version tables
+		a.False(db.Migrator().HasTable("goose_migration_missing"))
+
+	})
+}
@@ -0,0 +1,134 @@
+package repository_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/activatedio/datainfra/examples/data/model"
+	"github.com/activatedio/datainfra/examples/data/repository"
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
+	datatesting "github.com/activatedio/datainfra/pkg/data/testing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedOperation struct {
+	Name       string
+	Table      string
+	FetchType  data.FetchType
+	Rows       int64
+	ErrorClass string
+}
+
+type recordingInstrumentation struct {
+	lock       sync.Mutex
+	operations []recordedOperation
+}
+
+func (r *recordingInstrumentation) Start(ctx context.Context, op *gorm.Operation) (context.Context, func(result *gorm.OperationResult)) {
+	return ctx, func(result *gorm.OperationResult) {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		r.operations = append(r.operations, recordedOperation{
+			Name:       op.Name,
+			Table:      op.Table,
+			FetchType:  op.FetchType,
+			Rows:       result.Rows,
+			ErrorClass: result.ErrorClass,
+		})
+	}
+}
+
+func (r *recordingInstrumentation) take() []recordedOperation {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	res := r.operations
+	r.operations = nil
+	return res
+}
+
+func TestInstrumentation(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	instrumentation := &recordingInstrumentation{}
+
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, products repository.ProductRepository,
+		categories repository.CategoryRepository) {
+
+		ctx := cp.GetContext()
+		sku := uuid.New().String()
+		instrumentation.take()
+
+		r.NoError(products.Create(ctx, &model.Product{SKU: sku, Description: sku}))
+		a.Contains(instrumentation.take(), recordedOperation{
+			Name: gorm.OperationCreate, Table: "products", FetchType: data.FetchTypeNone, Rows: 1,
+		})
+
+		got, err := products.FindByKey(ctx, sku)
+		r.NoError(err)
+		r.NotNil(got)
+		a.Equal([]recordedOperation{{
+			Name: gorm.OperationDoFind, Table: "products", FetchType: data.FetchTypeDetail, Rows: 1,
+		}}, instrumentation.take())
+
+		_, err = products.ListAll(ctx, data.ListParams{})
+		r.NoError(err)
+		ops := instrumentation.take()
+		r.Len(ops, 1)
+		a.Equal(gorm.OperationDoList, ops[0].Name)
+		a.Equal(data.FetchType(data.FetchTypeList), ops[0].FetchType)
+		a.Positive(ops[0].Rows)
+
+		_, err = products.Search(ctx, []*data.SearchPredicate{{
+			Name:        "sku",
+			Operator:    data.SearchOperatorStringEquals,
+			StringValue: sku,
+		}}, data.ListParams{})
+		r.NoError(err)
+		a.Equal([]recordedOperation{{
+			Name: gorm.OperationSearch, Table: "products", FetchType: data.FetchTypeList, Rows: 1,
+		}}, instrumentation.take())
+
+		r.NoError(products.AssociateCategories(ctx, sku, []string{"a"}, nil))
+		a.Contains(instrumentation.take(), recordedOperation{
+			Name: gorm.OperationAssociate, Table: "product_categories", FetchType: data.FetchTypeNone, Rows: 1,
+		})
+
+		keys, err := categories.FilterKeys(ctx, []string{"a", "missing"})
+		r.NoError(err)
+		a.Equal([]string{"a"}, keys)
+		a.Equal([]recordedOperation{{
+			Name: gorm.OperationFilterKeys, Table: "categories", FetchType: data.FetchTypeKeys, Rows: 1,
+		}}, instrumentation.take())
+
+		a.ErrorIs(products.Update(ctx, &model.Product{SKU: uuid.New().String()}), data.ErrNotFound)
+		a.Contains(instrumentation.take(), recordedOperation{
+			Name: gorm.OperationUpdate, Table: "products", FetchType: data.FetchTypeNone, ErrorClass: gorm.ErrorClassNotFound,
+		})
+
+		r.NoError(products.AssociateCategories(ctx, sku, nil, []string{"a"}))
+		r.NoError(products.Delete(ctx, sku))
+		a.Contains(instrumentation.take(), recordedOperation{
+			Name: gorm.OperationDelete, Table: "products", FetchType: data.FetchTypeNone, Rows: 1,
+		})
+	}, func() gorm.Instrumentation {
+		return instrumentation
+	})
+}
+
+func TestErrorClass(t *testing.T) {
+	a := assert.New(t)
+
+	a.Empty(gorm.ErrorClass(nil))
+	a.Equal(gorm.ErrorClassNotFound, gorm.ErrorClass(data.ErrNotFound))
+	a.Equal(gorm.ErrorClassAlreadyExists, gorm.ErrorClass(data.ErrAlreadyExists))
+	a.Equal(gorm.ErrorClassConflict, gorm.ErrorClass(data.ErrForeignKeyViolation))
+	a.Equal(gorm.ErrorClassCanceled, gorm.ErrorClass(context.Canceled))
+	a.Equal(gorm.ErrorClassTimeout, gorm.ErrorClass(context.DeadlineExceeded))
+	a.Equal(gorm.ErrorClassConflict, gorm.ErrorClass(data.StaleVersion{}))
+	a.Equal(gorm.ErrorClassInternal, gorm.ErrorClass(assert.AnError))
+}
@@ -0,0 +1,181 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/activatedio/datainfra/examples/data/model"
+	"github.com/activatedio/datainfra/examples/data/repository"
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
+	datatesting "github.com/activatedio/datainfra/pkg/data/testing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListingRepository_Crud(t *testing.T) {
+	a := assert.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ListingRepository) {
+		datatesting.DoTestCrud[*model.Listing, repository.ListingKey](t, cp.GetContext(), unit,
+			&datatesting.CrudTestFixture[*model.Listing, repository.ListingKey]{
+				KeyExists:  repository.ListingKey{Market: "us", SKU: "1"},
+				KeyMissing: repository.ListingKey{Market: "us", SKU: "invalid"},
+				NewEntity: func() *model.Listing {
+					return &model.Listing{}
+				},
+				ExtractKey: func(e *model.Listing) repository.ListingKey {
+					return repository.ListingKey{Market: e.Market, SKU: e.SKU}
+				},
+				AssertDetailEntry: func(_ *testing.T, e *model.Listing) {
+					a.NotEmpty(e.Market)
+					a.NotEmpty(e.SKU)
+					a.NotEmpty(e.Description)
+				},
+				ModifyBeforeCreate: func(e *model.Listing) {
+					e.Market = "us"
+					e.SKU = uuid.New().String()
+					e.Description = "initial"
+				},
+				AssertAfterCreate: func(_ *testing.T, e *model.Listing) {
+					a.Equal("initial", e.Description)
+				},
+				ModifyBeforeUpdate: func(e *model.Listing) {
+					e.Description = "modified"
+				},
+				AssertAfterUpdate: func(_ *testing.T, e *model.Listing) {
+					a.Equal("modified", e.Description)
+				},
+			})
+	})
+}
+
+func TestListingRepository_FilterKeys(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ListingRepository) {
+
+		ctx := cp.GetContext()
+
+		// Keys sharing a market or a SKU with existing listings are only matched on both
+		got, err := unit.FilterKeys(ctx, []repository.ListingKey{
+			{Market: "us", SKU: "1"},
+			{Market: "eu", SKU: "2"},
+			{Market: "eu", SKU: "1"},
+			{Market: "missing", SKU: "1"},
+		})
+		r.NoError(err)
+		a.ElementsMatch([]repository.ListingKey{
+			{Market: "us", SKU: "1"},
+			{Market: "eu", SKU: "1"},
+		}, got)
+
+		got, err = unit.FilterKeys(ctx, nil)
+		r.NoError(err)
+		a.Empty(got)
+	})
+}
+
+func TestListingRepository_Batch(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ListingRepository) {
+
+		ctx := cp.GetContext()
+
+		sku := uuid.New().String()
+
+		results, err := unit.CreateMany(ctx, []*model.Listing{
+			{Market: "us", SKU: sku, Description: "us"},
+			{Market: "eu", SKU: sku, Description: "eu"},
+			{Market: "us", SKU: "1", Description: "duplicate"},
+		})
+		r.NoError(err)
+		r.Len(results, 3)
+		a.NoError(results[0])
+		a.NoError(results[1])
+		a.ErrorIs(results[2], data.ErrAlreadyExists)
+
+		keys := []repository.ListingKey{
+			{Market: "eu", SKU: sku},
+			{Market: "missing", SKU: sku},
+			{Market: "us", SKU: sku},
+		}
+
+		got, err := unit.FindByKeys(ctx, keys)
+		r.NoError(err)
+		r.Len(got, len(keys))
+		if a.NotNil(got[0]) {
+			a.Equal("eu", got[0].Description)
+		}
+		a.Nil(got[1])
+		if a.NotNil(got[2]) {
+			a.Equal("us", got[2].Description)
+		}
+
+		r.NoError(unit.DeleteByKeys(ctx, keys[:1]))
+
+		got, err = unit.FindByKeys(ctx, keys)
+		r.NoError(err)
+		a.Nil(got[0])
+		a.NotNil(got[2])
+	})
+}
+
+func TestListingRepository_Associate(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider,
+		unit repository.ListingRepository,
+		cr repository.CategoryRepository,
+		pr repository.ProductRepository,
+	) {
+
+		ctx := cp.GetContext()
+
+		sku := uuid.New().String()
+
+		keys := []repository.ListingKey{
+			{Market: "us", SKU: sku},
+			{Market: "eu", SKU: sku},
+		}
+
+		for _, k := range keys {
+			r.NoError(unit.Create(ctx, &model.Listing{Market: k.Market, SKU: k.SKU, Description: k.Market}))
+		}
+
+		name := uuid.New().String()
+		r.NoError(cr.Create(ctx, &model.Category{Name: name, Description: name}))
+
+		// Composite parent key
+		r.NoError(unit.AssociateCategories(ctx, keys[0], []string{name, "b"}, nil))
+
+		got, err := unit.ListByCategory(ctx, name, data.ListParams{})
+		r.NoError(err)
+		r.Len(got.List, 1)
+		a.Equal("us", got.List[0].Market)
+
+		cats, err := cr.ListByListing(ctx, keys[0], data.ListParams{})
+		r.NoError(err)
+		a.Len(cats.List, 2)
+
+		cats, err = cr.ListByListing(ctx, keys[1], data.ListParams{})
+		r.NoError(err)
+		a.Empty(cats.List)
+
+		r.NoError(unit.AssociateCategories(ctx, keys[0], nil, []string{"b"}))
+
+		cats, err = cr.ListByListing(ctx, keys[0], data.ListParams{})
+		r.NoError(err)
+		r.Len(cats.List, 1)
+		a.Equal(name, cats.List[0].Name)
+
+		// Composite child key, where missing children are skipped
+		r.NoError(pr.AssociateListings(ctx, "1", append(keys, repository.ListingKey{Market: "missing", SKU: sku}), nil))
+		r.NoError(pr.AssociateListings(ctx, "1", nil, keys[1:]))
+
+		var markets []string
+		r.NoError(gorm.GetDB(ctx).Table("product_listings").Where("product_sku = ? AND listing_sku = ?", "1", sku).
+			Pluck("listing_market", &markets).Error)
+		a.Equal([]string{"us"}, markets)
+	})
+}
@@ -2,12 +2,16 @@ package repository_test
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/activatedio/datainfra/examples/data/model"
 	"github.com/activatedio/datainfra/examples/data/repository"
 	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
 	datatesting "github.com/activatedio/datainfra/pkg/data/testing"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -17,39 +21,303 @@ import (
 func TestProductRepository_Search(t *testing.T) {
 	a := assert.New(t)
 	r := require.New(t)
-	datatesting.Run(t, AppFixtures, func(md *ProfileMetadata, cp datatesting.ContextProvider, unit repository.ProductRepository) {
+
+	entry := func(preds []*data.SearchPredicate, assert func(got *data.List[*data.SearchResult[*model.Product]], err error)) *datatesting.SearchTestFixtureEntry[*model.Product] {
+		return &datatesting.SearchTestFixtureEntry[*model.Product]{
+			Arrange: func(ctx context.Context) (context.Context, []*data.SearchPredicate) {
+				return ctx, preds
+			},
+			Assert: assert,
+		}
+	}
+
+	skus := func(got *data.List[*data.SearchResult[*model.Product]]) []string {
+		var res []string
+		for _, e := range got.List {
+			res = append(res, e.Entity.SKU)
+		}
+		return res
+	}
+
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ProductRepository) {
 		datatesting.DoTestSearch[*model.Product, repository.ProductRepository](t, cp.GetContext(), unit,
 			&datatesting.SearchTestFixture[*model.Product, repository.ProductRepository]{
 				FixtureEntries: func() map[string]*datatesting.SearchTestFixtureEntry[*model.Product] {
-					switch md.Name {
-					case "sqlite":
-						return map[string]*datatesting.SearchTestFixtureEntry[*model.Product]{}
-					case "postgres":
-						return map[string]*datatesting.SearchTestFixtureEntry[*model.Product]{
-							"keywords": {
-								Arrange: func(ctx context.Context) (context.Context, []*data.SearchPredicate) {
-									return ctx, []*data.SearchPredicate{
-										{
-											Name:        "keywords",
-											Operator:    data.SearchOperatorStringMatch,
-											StringValue: "Test",
-										},
-									}
-								},
-								Assert: func(got *data.List[*data.SearchResult[*model.Product]], err error) {
-									r.NoError(err)
-									a.Len(got.List, 2)
-								},
+					entries := map[string]*datatesting.SearchTestFixtureEntry[*model.Product]{
+						"sku equals": entry([]*data.SearchPredicate{
+							{
+								Name:        "sku",
+								Operator:    data.SearchOperatorStringEquals,
+								StringValue: "1",
 							},
-						}
-					default:
-						panic(fmt.Errorf("unexpected product name: %s", md.Name))
+						}, func(got *data.List[*data.SearchResult[*model.Product]], err error) {
+							r.NoError(err)
+							a.Equal([]string{"1"}, skus(got))
+						}),
+						"sku in": entry([]*data.SearchPredicate{
+							{
+								Name:             "sku",
+								Operator:         data.SearchOperatorStringIn,
+								StringArrayValue: []string{"1", "3", "missing"},
+							},
+						}, func(got *data.List[*data.SearchResult[*model.Product]], err error) {
+							r.NoError(err)
+							a.Equal([]string{"1", "3"}, skus(got))
+						}),
+						"sku in empty": entry([]*data.SearchPredicate{
+							{
+								Name:     "sku",
+								Operator: data.SearchOperatorStringIn,
+							},
+						}, func(got *data.List[*data.SearchResult[*model.Product]], err error) {
+							r.NoError(err)
+							a.Empty(got.List)
+						}),
+						"description match and sku not in": entry([]*data.SearchPredicate{
+							{
+								Name:        "description",
+								Operator:    data.SearchOperatorStringMatch,
+								StringValue: "TEST product",
+							},
+							{
+								Name:             "sku",
+								Operator:         data.SearchOperatorStringNotIn,
+								StringArrayValue: []string{"2"},
+							},
+						}, func(got *data.List[*data.SearchResult[*model.Product]], err error) {
+							r.NoError(err)
+							a.Equal([]string{"1"}, skus(got))
+						}),
+						"description match wildcard": entry([]*data.SearchPredicate{
+							{
+								Name:        "description",
+								Operator:    data.SearchOperatorStringMatch,
+								StringValue: "%",
+							},
+						}, func(got *data.List[*data.SearchResult[*model.Product]], err error) {
+							r.NoError(err)
+							a.Empty(got.List)
+						}),
+						"undeclared predicate": entry([]*data.SearchPredicate{
+							{
+								Name:        "unknown",
+								Operator:    data.SearchOperatorStringEquals,
+								StringValue: "1",
+							},
+						}, func(_ *data.List[*data.SearchResult[*model.Product]], err error) {
+							a.ErrorIs(err, data.ErrInvalidPredicate)
+						}),
+						"unsupported operator": entry([]*data.SearchPredicate{
+							{
+								Name:        "sku",
+								Operator:    data.SearchOperatorStringMatch,
+								StringValue: "1",
+							},
+						}, func(_ *data.List[*data.SearchResult[*model.Product]], err error) {
+							a.ErrorIs(err, data.ErrInvalidPredicate)
+						}),
 					}
+					entries["keywords"] = entry([]*data.SearchPredicate{
+						{
+							Name:        "@keywords",
+							Operator:    data.SearchOperatorStringMatch,
+							StringValue: "Test",
+						},
+					}, func(got *data.List[*data.SearchResult[*model.Product]], err error) {
+						r.NoError(err)
+						a.ElementsMatch([]string{"1", "2"}, skus(got))
+						for i, e := range got.List {
+							a.Positive(e.Score)
+							if i > 0 {
+								a.LessOrEqual(e.Score, got.List[i-1].Score)
+							}
+						}
+					})
+					entries["keywords and sku"] = entry([]*data.SearchPredicate{
+						{
+							Name:        "@keywords",
+							Operator:    data.SearchOperatorStringMatch,
+							StringValue: "product",
+						},
+						{
+							Name:             "sku",
+							Operator:         data.SearchOperatorStringIn,
+							StringArrayValue: []string{"2", "3"},
+						},
+					}, func(got *data.List[*data.SearchResult[*model.Product]], err error) {
+						r.NoError(err)
+						a.ElementsMatch([]string{"2", "3"}, skus(got))
+					})
+					entries["query"] = entry([]*data.SearchPredicate{
+						{
+							Name:        "@query",
+							Operator:    data.SearchOperatorStringMatch,
+							StringValue: "test -1",
+						},
+					}, func(got *data.List[*data.SearchResult[*model.Product]], err error) {
+						r.NoError(err)
+						a.Equal([]string{"2"}, skus(got))
+					})
+					entries["query phrase or"] = entry([]*data.SearchPredicate{
+						{
+							Name:        "@query",
+							Operator:    data.SearchOperatorStringMatch,
+							StringValue: `"product 3" or "product 4"`,
+						},
+					}, func(got *data.List[*data.SearchResult[*model.Product]], err error) {
+						r.NoError(err)
+						a.ElementsMatch([]string{"3", "4"}, skus(got))
+					})
+					return entries
 				},
 			})
 	})
 }
 
+func TestProductRepository_CountSearch(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ProductRepository) {
+
+		ctx := cp.GetContext()
+
+		count, err := unit.CountSearch(ctx, []*data.SearchPredicate{
+			{
+				Name:             "sku",
+				Operator:         data.SearchOperatorStringIn,
+				StringArrayValue: []string{"1", "3", "missing"},
+			},
+		}, data.ListParams{})
+		r.NoError(err)
+		a.Equal(int64(2), count)
+
+		count, err = unit.CountSearch(ctx, []*data.SearchPredicate{
+			{
+				Name:        "@keywords",
+				Operator:    data.SearchOperatorStringMatch,
+				StringValue: "Test",
+			},
+		}, data.ListParams{
+			PageParams: &data.PageParams{
+				Count: 1,
+			},
+		})
+		r.NoError(err)
+		a.Equal(int64(2), count)
+
+		_, err = unit.CountSearch(ctx, []*data.SearchPredicate{
+			{
+				Name:        "unknown",
+				Operator:    data.SearchOperatorStringEquals,
+				StringValue: "1",
+			},
+		}, data.ListParams{})
+		a.ErrorIs(err, data.ErrInvalidPredicate)
+	})
+}
+
+func TestProductRepository_SearchPaging(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ProductRepository) {
+
+		ctx := cp.GetContext()
+
+		preds := []*data.SearchPredicate{
+			{
+				Name:        "@keywords",
+				Operator:    data.SearchOperatorStringMatch,
+				StringValue: "product",
+			},
+		}
+
+		all, err := unit.Search(ctx, preds, data.ListParams{})
+		r.NoError(err)
+		a.Len(all.List, 4)
+
+		var got []string
+		token := ""
+
+		for {
+			page, err := unit.Search(ctx, preds, data.ListParams{
+				PageParams: &data.PageParams{
+					PageToken: token,
+					Count:     1,
+				},
+			})
+			r.NoError(err)
+			a.LessOrEqual(len(page.List), 1)
+			for _, e := range page.List {
+				got = append(got, e.Entity.SKU)
+			}
+			if page.NextPageToken == "" {
+				break
+			}
+			token = page.NextPageToken
+		}
+
+		var want []string
+		for _, e := range all.List {
+			want = append(want, e.Entity.SKU)
+		}
+
+		a.Equal(want, got)
+	})
+}
+
+func TestProductRepository_SearchPagingScoreTies(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ProductRepository) {
+
+		ctx := cp.GetContext()
+
+		// Products with the same description have the same score, and are ordered by their key
+		var want []string
+		for i := 0; i < 5; i++ {
+			sku := uuid.New().String()
+			r.NoError(unit.Create(ctx, &model.Product{SKU: sku, Description: "Tied widget"}))
+			want = append(want, sku)
+		}
+		slices.Sort(want)
+
+		preds := []*data.SearchPredicate{
+			{
+				Name:        "@keywords",
+				Operator:    data.SearchOperatorStringMatch,
+				StringValue: "widget",
+			},
+		}
+
+		var got []string
+		token := ""
+
+		for {
+			page, err := unit.Search(ctx, preds, data.ListParams{
+				PageParams: &data.PageParams{
+					PageToken: token,
+					Count:     2,
+				},
+			})
+			r.NoError(err)
+			for _, e := range page.List {
+				a.Positive(e.Score)
+				got = append(got, e.Entity.SKU)
+			}
+			if page.NextPageToken == "" {
+				break
+			}
+			token = page.NextPageToken
+		}
+
+		a.Equal(want, got)
+
+		for _, sku := range want {
+			r.NoError(unit.Delete(ctx, sku))
+		}
+	})
+}
+
 func TestProductRepository_Crud(t *testing.T) {
 	a := assert.New(t)
 	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ProductRepository) {
@@ -97,6 +365,176 @@ func TestProductRepository_ListByCategory(t *testing.T) {
 	})
 }
 
+func TestProductRepository_ListAllPaging(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ProductRepository) {
+
+		ctx := cp.GetContext()
+
+		for i := 0; i < 5; i++ {
+			s := uuid.New().String()
+			r.NoError(unit.Create(ctx, &model.Product{SKU: s, Description: s}))
+		}
+
+		all, err := unit.ListAll(ctx, data.ListParams{
+			PageParams: &data.PageParams{
+				Count: 1000,
+			},
+		})
+		r.NoError(err)
+		a.Empty(all.NextPageToken)
+
+		var got []string
+		token := ""
+
+		for {
+			page, err := unit.ListAll(ctx, data.ListParams{
+				PageParams: &data.PageParams{
+					PageToken: token,
+					Count:     2,
+				},
+			})
+			r.NoError(err)
+			a.LessOrEqual(len(page.List), 2)
+			for _, p := range page.List {
+				got = append(got, p.SKU)
+			}
+			if page.NextPageToken == "" {
+				break
+			}
+			token = page.NextPageToken
+		}
+
+		var want []string
+		for _, p := range all.List {
+			want = append(want, p.SKU)
+		}
+
+		a.Equal(want, got)
+
+		// Paging is ignored by counts
+		count, err := unit.Count(ctx, data.ListParams{
+			PageParams: &data.PageParams{
+				Count: 2,
+			},
+		})
+		r.NoError(err)
+		a.Equal(int64(len(want)), count)
+
+		_, err = unit.ListAll(ctx, data.ListParams{
+			PageParams: &data.PageParams{
+				PageToken: "invalid",
+			},
+		})
+		a.Error(err)
+	})
+}
+
+func TestProductRepository_ListAllSorted(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ProductRepository) {
+
+		ctx := cp.GetContext()
+
+		for i := 0; i < 5; i++ {
+			r.NoError(unit.Create(ctx, &model.Product{SKU: uuid.New().String(), Description: "same"}))
+		}
+
+		// Descriptions are nullable, and NULL is read and sorted as empty
+		var nulls []string
+		for i := 0; i < 3; i++ {
+			sku := uuid.New().String()
+			r.NoError(gorm.GetDB(ctx).Exec("INSERT INTO products (sku, description) VALUES (?, NULL)", sku).Error)
+			nulls = append(nulls, sku)
+		}
+
+		for _, direction := range []data.SortDirection{data.SortDirectionDescending, data.SortDirectionAscending} {
+
+			sort := []data.SortCriterion{
+				{Field: "description", Direction: direction},
+			}
+
+			all, err := unit.ListAll(ctx, data.ListParams{
+				PageParams: &data.PageParams{
+					Count: 1000,
+				},
+				Sort: sort,
+			})
+			r.NoError(err)
+
+			for i := 1; i < len(all.List); i++ {
+				if direction == data.SortDirectionDescending {
+					a.GreaterOrEqual(all.List[i-1].Description, all.List[i].Description)
+				} else {
+					a.LessOrEqual(all.List[i-1].Description, all.List[i].Description)
+				}
+			}
+
+			var got []string
+			token := ""
+
+			for {
+				page, err := unit.ListAll(ctx, data.ListParams{
+					PageParams: &data.PageParams{
+						PageToken: token,
+						Count:     2,
+					},
+					Sort: sort,
+				})
+				r.NoError(err)
+				for _, p := range page.List {
+					got = append(got, p.SKU)
+				}
+				if page.NextPageToken == "" {
+					break
+				}
+				token = page.NextPageToken
+			}
+
+			var want []string
+			for _, p := range all.List {
+				want = append(want, p.SKU)
+			}
+
+			a.Equal(want, got)
+			a.Subset(got, nulls)
+		}
+
+		sort := []data.SortCriterion{
+			{Field: "description", Direction: data.SortDirectionDescending},
+		}
+
+		first, err := unit.ListAll(ctx, data.ListParams{
+			PageParams: &data.PageParams{
+				Count: 1,
+			},
+			Sort: sort,
+		})
+		r.NoError(err)
+
+		// Token was issued for a different sort order
+		_, err = unit.ListAll(ctx, data.ListParams{
+			PageParams: &data.PageParams{
+				PageToken: first.NextPageToken,
+			},
+		})
+		a.Error(err)
+
+		_, err = unit.ListAll(ctx, data.ListParams{
+			Sort: []data.SortCriterion{
+				{Field: "unknown"},
+			},
+		})
+		a.Error(err)
+
+		for _, sku := range nulls {
+			r.NoError(unit.Delete(ctx, sku))
+		}
+	})
+}
+
 func TestProductRepository_GetSearchPredicates(t *testing.T) {
 	a := assert.New(t)
 	r := require.New(t)
@@ -121,6 +559,24 @@ func TestProductRepository_GetSearchPredicates(t *testing.T) {
 					data.SearchOperatorStringMatch,
 				},
 			},
+			{
+				Name:  "sku",
+				Label: "SKU",
+				Operators: []data.SearchOperator{
+					data.SearchOperatorStringEquals,
+					data.SearchOperatorStringNotEquals,
+					data.SearchOperatorStringIn,
+					data.SearchOperatorStringNotIn,
+				},
+			},
+			{
+				Name:  "description",
+				Label: "Description",
+				Operators: []data.SearchOperator{
+					data.SearchOperatorStringEquals,
+					data.SearchOperatorStringMatch,
+				},
+			},
 		}, got)
 	})
 }
@@ -191,3 +647,204 @@ func TestProductRepository_Associate(t *testing.T) {
 
 	})
 }
+
+func TestProductRepository_Batch(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ProductRepository) {
+
+		ctx := cp.GetContext()
+
+		var skus []string
+		var products []*model.Product
+
+		// More than the default batch size, so that several statements are used
+		for i := 0; i < gorm.DefaultBatchSize+5; i++ {
+			sku := uuid.New().String()
+			skus = append(skus, sku)
+			products = append(products, &model.Product{SKU: sku, Description: sku})
+		}
+
+		results, err := unit.CreateMany(data.WithActor(ctx, "batch"), append(products,
+			// Existing, and repeated in the batch
+			&model.Product{SKU: "1", Description: "duplicate"},
+			&model.Product{SKU: skus[0], Description: "duplicate"},
+		))
+		r.NoError(err)
+		r.Len(results, len(skus)+2)
+		for _, res := range results[:len(skus)] {
+			a.NoError(res)
+		}
+		a.ErrorIs(results[len(skus)], data.ErrAlreadyExists)
+		a.ErrorIs(results[len(skus)+1], data.ErrAlreadyExists)
+
+		// The audit columns are recorded for each created entity
+		audit := getAuditRecord(ctx, r, "products", "sku = ?", skus[len(skus)-1])
+		a.Equal("batch", audit.CreatedBy)
+		a.Equal("batch", audit.UpdatedBy)
+
+		missing := uuid.New().String()
+		keys := []string{skus[len(skus)-1], missing, skus[0], "1"}
+
+		got, err := unit.FindByKeys(ctx, keys)
+		r.NoError(err)
+		r.Len(got, len(keys))
+		if a.NotNil(got[0]) {
+			a.Equal(skus[len(skus)-1], got[0].SKU)
+		}
+		a.Nil(got[1])
+		if a.NotNil(got[2]) {
+			a.Equal(skus[0], got[2].SKU)
+			a.Equal(skus[0], got[2].Description)
+		}
+		if a.NotNil(got[3]) {
+			a.Equal("Test Product 1", got[3].Description)
+		}
+
+		r.NoError(unit.UpdateMany(ctx, []*model.Product{
+			{SKU: skus[0], Description: "modified 0"},
+			{SKU: skus[1], Description: "modified 1"},
+		}))
+
+		// Either all or none are updated
+		a.ErrorIs(unit.UpdateMany(ctx, []*model.Product{
+			{SKU: skus[0], Description: "modified again"},
+			{SKU: missing, Description: "modified again"},
+		}), data.ErrNotFound)
+
+		got, err = unit.FindByKeys(ctx, skus[:2])
+		r.NoError(err)
+		a.Equal("modified 0", got[0].Description)
+		a.Equal("modified 1", got[1].Description)
+
+		r.NoError(unit.DeleteByKeys(ctx, append(skus, missing)))
+
+		got, err = unit.FindByKeys(ctx, skus)
+		r.NoError(err)
+		for _, p := range got {
+			a.Nil(p)
+		}
+	})
+}
+
+// outboxPublisher records the published events, failing for the entity keys and operations in fail, and calling
+// onPublish before publishing.
+type outboxPublisher struct {
+	published []*data.Event
+	fail      map[string]bool
+	onPublish func()
+}
+
+func (p *outboxPublisher) Publish(_ context.Context, event *data.Event) error {
+	if p.onPublish != nil {
+		p.onPublish()
+	}
+	if p.fail[event.EntityKey+" "+string(event.Operation)] {
+		return errors.New("unavailable")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+func TestProductRepository_Outbox(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ProductRepository,
+		relay gorm.OutboxRelay, publisher data.Publisher) {
+
+		ctx := cp.GetContext()
+		pub := publisher.(*outboxPublisher)
+
+		// Drain the events of other tests
+		for {
+			n, err := relay.RelayOnce(ctx)
+			r.NoError(err)
+			if n == 0 {
+				break
+			}
+		}
+		pub.published = nil
+
+		sku1 := uuid.New().String()
+		sku2 := uuid.New().String()
+
+		r.NoError(unit.Create(ctx, &model.Product{SKU: sku1, Description: "initial"}))
+		r.NoError(unit.Update(ctx, &model.Product{SKU: sku1, Description: "modified"}))
+		_, err := unit.CreateMany(ctx, []*model.Product{{SKU: sku2, Description: "batch"}})
+		r.NoError(err)
+		r.NoError(unit.UpdateMany(ctx, []*model.Product{{SKU: sku2, Description: "batch modified"}}))
+		r.NoError(unit.Delete(ctx, sku1))
+
+		// Changes rolled back are not published
+		r.Error(gorm.InTx(ctx, func(ctx context.Context) error {
+			r.NoError(unit.Create(ctx, &model.Product{SKU: uuid.New().String(), Description: "rolled back"}))
+			return errors.New("rollback")
+		}))
+
+		key := func(sku string) string {
+			return `"` + sku + `"`
+		}
+		descriptionOf := func(payload json.RawMessage) string {
+			var v struct {
+				Description string
+			}
+			r.NoError(json.Unmarshal(payload, &v))
+			return v.Description
+		}
+
+		// The later events of an entity whose event fails to be published are held back
+		pub.fail = map[string]bool{key(sku2) + " CREATE": true}
+
+		// Events are published outside of the transaction claiming them, and a concurrent relay skips the claimed
+		// events and the later events of their entities
+		var concurrent []int
+		pub.onPublish = func() {
+			n, err := relay.RelayOnce(ctx)
+			r.NoError(err)
+			concurrent = append(concurrent, n)
+		}
+
+		n, err := relay.RelayOnce(ctx)
+		a.Error(err)
+		a.Equal(3, n)
+		a.Equal([]int{0, 0, 0, 0}, concurrent)
+		pub.onPublish = nil
+		r.Len(pub.published, 3)
+
+		for _, e := range pub.published {
+			a.Equal("Product", e.EntityType)
+			a.Equal(key(sku1), e.EntityKey)
+		}
+		a.Equal(data.HistoryOperationCreate, pub.published[0].Operation)
+		a.Equal("initial", descriptionOf(pub.published[0].Payload))
+		a.Equal(data.HistoryOperationUpdate, pub.published[1].Operation)
+		a.Equal("modified", descriptionOf(pub.published[1].Payload))
+		a.Equal(data.HistoryOperationDelete, pub.published[2].Operation)
+		a.Equal("modified", descriptionOf(pub.published[2].Payload))
+		a.Less(pub.published[0].ID, pub.published[1].ID)
+
+		// Once publishing succeeds, the held back events are published
+		pub.fail = nil
+		pub.published = nil
+
+		n, err = relay.RelayOnce(ctx)
+		r.NoError(err)
+		a.Equal(2, n)
+		r.Len(pub.published, 2)
+		a.Equal(key(sku2), pub.published[0].EntityKey)
+		a.Equal(data.HistoryOperationCreate, pub.published[0].Operation)
+		a.Equal("batch", descriptionOf(pub.published[0].Payload))
+		a.Equal(data.HistoryOperationUpdate, pub.published[1].Operation)
+		a.Equal("batch modified", descriptionOf(pub.published[1].Payload))
+
+		n, err = relay.RelayOnce(ctx)
+		r.NoError(err)
+		a.Zero(n)
+
+	}, gorm.NewOutboxRelay, func() data.Publisher {
+		return &outboxPublisher{}
+	}, func() *gorm.OutboxConfig {
+		// Polling is left to the test
+		return &gorm.OutboxConfig{PollInterval: time.Hour}
+	})
+}
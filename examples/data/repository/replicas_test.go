@@ -0,0 +1,168 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/activatedio/datainfra/examples/data/model"
+	"github.com/activatedio/datainfra/examples/data/repository"
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
+	datatesting "github.com/activatedio/datainfra/pkg/data/testing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestReadFromReplica(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, config *gorm.Config,
+		unit repository.CategoryRepository) {
+
+		// The replica is simulated with a separate sqlite database, holding a category the primary does not
+		if config.Dialect != gorm.DialectSqlite {
+			return
+		}
+
+		primary, err := gorm.NewDB(config)
+		r.NoError(err)
+
+		replica, err := gorm.NewDB(&gorm.Config{
+			Dialect: gorm.DialectSqlite,
+			Name:    filepath.Join(t.TempDir(), "replica"),
+		})
+		r.NoError(err)
+
+		r.NoError(replica.Exec(`CREATE TABLE categories (
+			name VARCHAR(64) PRIMARY KEY,
+			description VARCHAR(200),
+			version INTEGER NOT NULL DEFAULT 0,
+			deleted_at TIMESTAMP NULL,
+			labels TEXT NOT NULL DEFAULT '{}'
+		)`).Error)
+		r.NoError(replica.Exec("INSERT INTO categories (name, description) VALUES (?, ?)", "replica", "replica").Error)
+
+		r.NoError(gorm.RegisterReplicas(primary, replica))
+
+		ctx := gorm.WithDB(cp.GetContext(), primary)
+		name := uuid.New().String()
+
+		// Writes go to the primary
+		r.NoError(unit.Create(ctx, &model.Category{Name: name, Description: name}))
+
+		// Reads go to the replica
+		got, err := unit.FindByKey(ctx, "replica")
+		r.NoError(err)
+		a.NotNil(got)
+
+		got, err = unit.FindByKey(ctx, name)
+		a.ErrorIs(err, data.ErrNotFound)
+		a.Nil(got)
+
+		exists, err := unit.ExistsByKey(ctx, "replica")
+		r.NoError(err)
+		a.True(exists)
+
+		list, err := unit.ListAll(ctx, data.ListParams{})
+		r.NoError(err)
+		r.Len(list.List, 1)
+		a.Equal("replica", list.List[0].Name)
+
+		// Unless forced to the primary
+		got, err = unit.FindByKey(data.WithPrimary(ctx), name)
+		r.NoError(err)
+		a.NotNil(got)
+
+		// Or within a transaction
+		r.NoError(gorm.InTx(ctx, func(ctx context.Context) error {
+			got, err := unit.FindByKey(ctx, name)
+			a.NotNil(got)
+			return err
+		}))
+
+		// The query routed to a replica is a copy, so that later statements of the query passed in go to the primary
+		base := primary.WithContext(ctx).Table("categories").Where("name = ?", name)
+		var count int64
+
+		r.NoError(gorm.ReadFromReplica(ctx, base, data.FetchTypeList).Count(&count).Error)
+		a.Zero(count)
+		r.NoError(base.Count(&count).Error)
+		a.Equal(int64(1), count)
+
+		r.NoError(unit.Update(ctx, &model.Category{Name: name, Description: "modified"}))
+
+		got, err = unit.FindByKey(data.WithPrimary(ctx), name)
+		r.NoError(err)
+		r.NotNil(got)
+		a.Equal("modified", got.Description)
+
+		r.NoError(unit.Purge(ctx, name))
+
+		// Replicas are checked for health
+		checker := gorm.NewHealthChecker(gorm.HealthCheckerParams{
+			Lifecycle: fxtest.NewLifecycle(t),
+			DB:        primary,
+		})
+
+		health := checker.Check(ctx)
+		r.NoError(health.Err)
+		r.Len(health.Replicas, 1)
+		a.NoError(health.Replicas[0].Err)
+		a.Positive(health.Replicas[0].PoolStats.OpenConnections)
+
+		// Closing the primary closes the replicas
+		r.NoError(gorm.CloseDB(primary))
+
+		replicaDB, err := replica.DB()
+		r.NoError(err)
+		a.ErrorContains(replicaDB.Ping(), "database is closed")
+
+		primaryDB, err := primary.DB()
+		r.NoError(err)
+		a.ErrorContains(primaryDB.Ping(), "database is closed")
+	})
+}
+
+func TestReadFromReplica_Unreachable(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, config *gorm.Config) {
+
+		if config.Dialect != gorm.DialectSqlite {
+			return
+		}
+
+		primary, err := gorm.NewDB(config)
+		r.NoError(err)
+		defer func() {
+			r.NoError(gorm.CloseDB(primary))
+		}()
+
+		replica, err := gorm.NewDB(&gorm.Config{
+			Dialect: gorm.DialectSqlite,
+			Name:    filepath.Join(t.TempDir(), "replica"),
+		})
+		r.NoError(err)
+
+		r.NoError(gorm.RegisterReplicas(primary, replica))
+
+		replicaDB, err := replica.DB()
+		r.NoError(err)
+		r.NoError(replicaDB.Close())
+
+		checker := gorm.NewHealthChecker(gorm.HealthCheckerParams{
+			Lifecycle: fxtest.NewLifecycle(t),
+			DB:        primary,
+		})
+
+		// The primary is reachable, but reads would fail
+		health := checker.Check(cp.GetContext())
+		a.False(health.Ready())
+		a.ErrorContains(health.Err, "replica 0: ping:")
+		r.Len(health.Replicas, 1)
+		a.Error(health.Replicas[0].Err)
+	})
+}
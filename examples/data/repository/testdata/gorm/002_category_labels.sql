@@ -0,0 +1,4 @@
+-- +goose Up
+
+UPDATE categories SET labels = '{"env": "prod", "tier": "1"}' WHERE name = 'a';
+UPDATE categories SET labels = '{"env": "dev"}' WHERE name = 'b';
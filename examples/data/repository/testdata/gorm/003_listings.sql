@@ -0,0 +1,13 @@
+-- +goose Up
+
+INSERT INTO listings (market, sku, description) VALUES
+  ('us', '1', 'US Listing 1'),
+  ('us', '2', 'US Listing 2'),
+  ('eu', '1', 'EU Listing 1')
+;
+
+INSERT INTO listing_categories (listing_market, listing_sku, category_name, created_at) VALUES
+  ('us', '1', 'a', CURRENT_TIMESTAMP),
+  ('eu', '1', 'a', CURRENT_TIMESTAMP),
+  ('us', '2', 'b', CURRENT_TIMESTAMP)
+;
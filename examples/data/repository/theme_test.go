@@ -1,13 +1,20 @@
 package repository_test
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/activatedio/datainfra/examples/data/model"
 	"github.com/activatedio/datainfra/examples/data/repository"
+	repogorm "github.com/activatedio/datainfra/examples/data/repository/gorm"
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
 	datatesting "github.com/activatedio/datainfra/pkg/data/testing"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestThemeRepository_Crud(t *testing.T) {
@@ -40,6 +47,225 @@ func TestThemeRepository_Crud(t *testing.T) {
 				AssertAfterUpdate: func(_ *testing.T, e *model.Theme) {
 					a.Equal("modified", e.Description)
 				},
+				OtherScopeContext: func(ctx context.Context) context.Context {
+					return model.WithTenant(ctx, "1")
+				},
 			})
 	})
 }
+
+func TestThemeRepository_Upsert(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ThemeRepository) {
+
+		ctx := model.WithTenant(cp.GetContext(), "2")
+		otherCtx := model.WithTenant(cp.GetContext(), "1")
+		name := uuid.New().String()
+
+		inserted, err := unit.Upsert(ctx, &model.Theme{Name: name, Description: "initial"})
+		r.NoError(err)
+		a.True(inserted)
+
+		inserted, err = unit.Upsert(ctx, &model.Theme{Name: name, Description: "modified"})
+		r.NoError(err)
+		a.False(inserted)
+
+		got, err := unit.FindByKey(ctx, name)
+		r.NoError(err)
+		if a.NotNil(got) {
+			a.Equal("modified", got.Description)
+		}
+
+		// The tenant is part of the conflict target, so the other tenant gets its own row
+		inserted, err = unit.Upsert(otherCtx, &model.Theme{Name: name, Description: "other"})
+		r.NoError(err)
+		a.True(inserted)
+
+		got, err = unit.FindByKey(ctx, name)
+		r.NoError(err)
+		if a.NotNil(got) {
+			a.Equal("modified", got.Description)
+		}
+
+		r.NoError(unit.Delete(ctx, name))
+		r.NoError(unit.Delete(otherCtx, name))
+	})
+}
+
+func TestThemeRepository_UpsertScopeViolation(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider) {
+
+		ctx := model.WithTenant(cp.GetContext(), "2")
+		otherCtx := model.WithTenant(cp.GetContext(), "1")
+		db := gorm.GetDB(ctx)
+		name := uuid.New().String()
+
+		// Names are unique across tenants, so that upserts of other tenants conflict
+		r.NoError(db.Exec(`CREATE TABLE IF NOT EXISTS global_themes (
+			tenant_id VARCHAR(64) NOT NULL, name VARCHAR(64) PRIMARY KEY, description VARCHAR(200))`).Error)
+
+		template := gorm.NewMappingTemplate[*model.Theme, *repogorm.ThemeInternal](gorm.MappingTemplateParams[*model.Theme, *repogorm.ThemeInternal]{
+			ContextScope: repogorm.WithTenantScope(),
+			Table:        "global_themes",
+			KeyColumns:   []string{"name"},
+			ToInternal: func(m *model.Theme) *repogorm.ThemeInternal {
+				return &repogorm.ThemeInternal{Theme: m}
+			},
+			FromInternal: func(m *repogorm.ThemeInternal) *model.Theme {
+				return m.Theme
+			},
+		})
+		unit := gorm.NewMappingUpsertTemplate[*model.Theme, *repogorm.ThemeInternal, string](gorm.MappingCrudTemplateImplOptions[*model.Theme, *repogorm.ThemeInternal, string]{
+			Template:    template,
+			FindBuilder: gorm.SingleFindBuilder[string]("global_themes.name"),
+		})
+
+		inserted, err := unit.Upsert(ctx, &model.Theme{Name: name, Description: "initial"})
+		r.NoError(err)
+		a.True(inserted)
+
+		inserted, err = unit.Upsert(otherCtx, &model.Theme{Name: name, Description: "other"})
+		a.ErrorIs(err, data.ErrScopeViolation)
+		a.False(inserted)
+
+		inserted, err = unit.Upsert(ctx, &model.Theme{Name: name, Description: "modified"})
+		r.NoError(err)
+		a.False(inserted)
+
+		var got repogorm.ThemeInternal
+		r.NoError(db.Table("global_themes").Where("name = ?", name).Take(&got).Error)
+		a.Equal("2", got.TenantID)
+		a.Equal("modified", got.Description)
+
+		r.NoError(db.Exec("DELETE FROM global_themes WHERE name = ?", name).Error)
+	})
+}
+
+func TestThemeRepository_CreateMany(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ThemeRepository) {
+
+		ctx := model.WithTenant(cp.GetContext(), "2")
+		otherCtx := model.WithTenant(cp.GetContext(), "1")
+		shared, other := uuid.New().String(), uuid.New().String()
+
+		r.NoError(unit.Create(otherCtx, &model.Theme{Name: shared, Description: "other"}))
+		r.NoError(unit.Create(ctx, &model.Theme{Name: other, Description: "existing"}))
+
+		// A key of the other tenant neither exists nor conflicts within this tenant
+		results, err := unit.CreateMany(ctx, []*model.Theme{
+			{Name: shared, Description: "created"},
+			{Name: other, Description: "duplicate"},
+		})
+		r.NoError(err)
+		r.Len(results, 2)
+		a.NoError(results[0])
+		a.ErrorIs(results[1], data.ErrAlreadyExists)
+
+		got, err := unit.FindByKeys(ctx, []string{shared, other})
+		r.NoError(err)
+		r.Len(got, 2)
+		if a.NotNil(got[0]) {
+			a.Equal("created", got[0].Description)
+		}
+		if a.NotNil(got[1]) {
+			a.Equal("existing", got[1].Description)
+		}
+
+		found, err := unit.FindByKey(otherCtx, shared)
+		r.NoError(err)
+		a.Equal("other", found.Description)
+
+		r.NoError(unit.DeleteByKeys(ctx, []string{shared, other}))
+		r.NoError(unit.Delete(otherCtx, shared))
+	})
+}
+
+func TestThemeRepository_History(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ThemeRepository) {
+
+		ctx := model.WithTenant(cp.GetContext(), "2")
+		otherCtx := model.WithTenant(cp.GetContext(), "1")
+		name := uuid.New().String()
+
+		r.NoError(unit.Create(ctx, &model.Theme{Name: name, Description: "initial"}))
+		r.NoError(unit.Update(ctx, &model.Theme{Name: name, Description: "modified"}))
+
+		// The other tenant has an entity with the same name, whose history is its own
+		r.NoError(unit.Create(otherCtx, &model.Theme{Name: name, Description: "other"}))
+
+		got, err := unit.ListHistory(ctx, name, data.ListParams{})
+		r.NoError(err)
+		r.Len(got.List, 2)
+		a.Equal(data.HistoryOperationCreate, got.List[0].Operation)
+		a.Equal(data.HistoryOperationUpdate, got.List[1].Operation)
+
+		got, err = unit.ListHistory(otherCtx, name, data.ListParams{})
+		r.NoError(err)
+		r.Len(got.List, 1)
+		a.Equal(data.HistoryOperationCreate, got.List[0].Operation)
+
+		// The history remains within the scope after the entity is deleted
+		r.NoError(unit.Delete(ctx, name))
+
+		got, err = unit.ListHistory(ctx, name, data.ListParams{})
+		r.NoError(err)
+		a.Len(got.List, 3)
+
+		got, err = unit.ListHistory(model.WithTenant(cp.GetContext(), "3"), name, data.ListParams{})
+		r.NoError(err)
+		a.Empty(got.List)
+
+		r.NoError(unit.Delete(otherCtx, name))
+	})
+}
+
+func TestThemeRepository_HistoryConcurrentUpdates(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit repository.ThemeRepository) {
+
+		ctx := model.WithTenant(cp.GetContext(), "2")
+		name := uuid.New().String()
+
+		r.NoError(unit.Create(ctx, &model.Theme{Name: name, Description: "initial"}))
+
+		const updates = 5
+
+		var wg sync.WaitGroup
+		errs := make(chan error, updates)
+
+		for i := range updates {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- unit.Update(ctx, &model.Theme{Name: name, Description: fmt.Sprintf("update %d", i)})
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			r.NoError(err)
+		}
+
+		got, err := unit.ListHistory(ctx, name, data.ListParams{})
+		r.NoError(err)
+		r.Len(got.List, updates+1)
+
+		// Each change starts from the snapshot the previous change ended with
+		for i := 1; i < len(got.List); i++ {
+			a.Equal(data.HistoryOperationUpdate, got.List[i].Operation)
+			a.JSONEq(string(got.List[i-1].After), string(got.List[i].Before))
+		}
+
+		r.NoError(unit.Delete(ctx, name))
+	})
+}
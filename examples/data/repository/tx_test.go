@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/activatedio/datainfra/examples/data/model"
+	"github.com/activatedio/datainfra/examples/data/repository"
+	"github.com/activatedio/datainfra/pkg/data"
+	datatesting "github.com/activatedio/datainfra/pkg/data/testing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager_InTx(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	datatesting.Run(t, AppFixtures, func(cp datatesting.ContextProvider, unit data.TxManager, pr repository.ProductRepository) {
+
+		ctx := cp.GetContext()
+
+		exists := func(sku string) bool {
+			got, err := pr.FindByKey(ctx, sku)
+			if errors.Is(err, data.ErrNotFound) {
+				return false
+			}
+			r.NoError(err)
+			return got != nil
+		}
+
+		committed := uuid.New().String()
+		rolledBack := uuid.New().String()
+		outer := uuid.New().String()
+		inner := uuid.New().String()
+
+		r.NoError(unit.InTx(ctx, func(ctx context.Context) error {
+			return pr.Create(ctx, &model.Product{SKU: committed, Description: committed})
+		}))
+
+		a.True(exists(committed))
+
+		fail := errors.New("fail")
+
+		a.ErrorIs(unit.InTx(ctx, func(ctx context.Context) error {
+			r.NoError(pr.Create(ctx, &model.Product{SKU: rolledBack, Description: rolledBack}))
+			return fail
+		}), fail)
+
+		a.False(exists(rolledBack))
+
+		// Nested transaction is rolled back to the savepoint only
+		r.NoError(unit.InTx(ctx, func(ctx context.Context) error {
+			r.NoError(pr.Create(ctx, &model.Product{SKU: outer, Description: outer}))
+			a.ErrorIs(unit.InTx(ctx, func(ctx context.Context) error {
+				r.NoError(pr.Create(ctx, &model.Product{SKU: inner, Description: inner}))
+				return fail
+			}), fail)
+			return nil
+		}))
+
+		a.True(exists(outer))
+		a.False(exists(inner))
+
+		readOnly := uuid.New().String()
+
+		a.Error(unit.InTx(ctx, func(ctx context.Context) error {
+			got, err := pr.FindByKey(ctx, committed)
+			r.NoError(err)
+			a.NotNil(got)
+			return pr.Create(ctx, &model.Product{SKU: readOnly, Description: readOnly})
+		}, data.WithReadOnly()))
+
+		a.False(exists(readOnly))
+
+		// Nested transactions may not ask for other options than the outer transaction
+		r.NoError(unit.InTx(ctx, func(ctx context.Context) error {
+			called := false
+			a.ErrorIs(unit.InTx(ctx, func(ctx context.Context) error {
+				called = true
+				return nil
+			}, data.WithReadOnly()), data.ErrNestedTxOptions)
+			a.False(called)
+			a.ErrorIs(unit.InTx(ctx, func(ctx context.Context) error {
+				return nil
+			}, data.WithIsolation(sql.LevelSerializable)), data.ErrNestedTxOptions)
+			return nil
+		}))
+
+		r.NoError(unit.InTx(ctx, func(ctx context.Context) error {
+			r.NoError(unit.InTx(ctx, func(ctx context.Context) error {
+				_, err := pr.FindByKey(ctx, committed)
+				return err
+			}, data.WithReadOnly()))
+			// Without options, the nested transaction takes those of the outer transaction
+			return unit.InTx(ctx, func(ctx context.Context) error {
+				_, err := pr.FindByKey(ctx, committed)
+				return err
+			})
+		}, data.WithReadOnly()))
+
+		// Connection is writable after a read only transaction
+		r.NoError(pr.Delete(ctx, committed))
+	})
+}
@@ -12,26 +12,44 @@ type CategoryRepository interface {
 	FindByKey(context.Context, string) (*model.Category, error)
 	ExistsByKey(context.Context, string) (bool, error)
 	ListAll(context.Context, data.ListParams) (*data.List[*model.Category], error)
+	Count(context.Context, data.ListParams) (int64, error)
 	Create(context.Context, *model.Category) error
 	Update(context.Context, *model.Category) error
 	Delete(context.Context, string) error
 	DeleteEntity(context.Context, *model.Category) error
+	FindByKeys(context.Context, []string) ([]*model.Category, error)
+	CreateMany(context.Context, []*model.Category) ([]error, error)
+	UpdateMany(context.Context, []*model.Category) error
+	DeleteByKeys(context.Context, []string) error
+	Upsert(context.Context, *model.Category) (bool, error)
+	Patch(context.Context, string, []string, *model.Category) error
 	FilterKeys(ctx context.Context, keys []string) ([]string, error)
 	ListByProduct(ctx context.Context, key string, params data.ListParams) (*data.List[*model.Category], error)
+	ListByListing(ctx context.Context, key ListingKey, params data.ListParams) (*data.List[*model.Category], error)
+	Restore(context.Context, string) error
+	Purge(context.Context, string) error
+	ListHistory(context.Context, string, data.ListParams) (*data.List[*data.HistoryEntry], error)
 }
 
 // ProductRepository is a repository for the type Product
 type ProductRepository interface {
 	ListAll(context.Context, data.ListParams) (*data.List[*model.Product], error)
+	Count(context.Context, data.ListParams) (int64, error)
 	Create(context.Context, *model.Product) error
 	Update(context.Context, *model.Product) error
 	Delete(context.Context, string) error
 	DeleteEntity(context.Context, *model.Product) error
 	FindByKey(context.Context, string) (*model.Product, error)
 	ExistsByKey(context.Context, string) (bool, error)
-	Search(ctx context.Context, criteria []*data.SearchPredicate, params *data.PageParams) (*data.List[*data.SearchResult[*model.Product]], error)
+	FindByKeys(context.Context, []string) ([]*model.Product, error)
+	CreateMany(context.Context, []*model.Product) ([]error, error)
+	UpdateMany(context.Context, []*model.Product) error
+	DeleteByKeys(context.Context, []string) error
+	Search(ctx context.Context, criteria []*data.SearchPredicate, params data.ListParams) (*data.List[*data.SearchResult[*model.Product]], error)
 	GetSearchPredicates(context.Context) ([]*data.SearchPredicateDescriptor, error)
+	CountSearch(ctx context.Context, criteria []*data.SearchPredicate, params data.ListParams) (int64, error)
 	AssociateCategories(ctx context.Context, key string, add []string, remove []string) error
+	AssociateListings(ctx context.Context, key string, add []ListingKey, remove []ListingKey) error
 	ListByCategory(ctx context.Context, key string, params data.ListParams) (*data.List[*model.Product], error)
 }
 
@@ -42,6 +60,38 @@ type ThemeRepository interface {
 	FindByKey(context.Context, string) (*model.Theme, error)
 	ExistsByKey(context.Context, string) (bool, error)
 	ListAll(context.Context, data.ListParams) (*data.List[*model.Theme], error)
+	Count(context.Context, data.ListParams) (int64, error)
 	Create(context.Context, *model.Theme) error
 	Update(context.Context, *model.Theme) error
+	FindByKeys(context.Context, []string) ([]*model.Theme, error)
+	CreateMany(context.Context, []*model.Theme) ([]error, error)
+	UpdateMany(context.Context, []*model.Theme) error
+	DeleteByKeys(context.Context, []string) error
+	Upsert(context.Context, *model.Theme) (bool, error)
+	ListHistory(context.Context, string, data.ListParams) (*data.List[*data.HistoryEntry], error)
+}
+
+// ListingKey is the key for Listing
+type ListingKey struct {
+	Market string
+	SKU    string
+}
+
+// ListingRepository is a repository for the type Listing
+type ListingRepository interface {
+	FindByKey(context.Context, ListingKey) (*model.Listing, error)
+	ExistsByKey(context.Context, ListingKey) (bool, error)
+	ListAll(context.Context, data.ListParams) (*data.List[*model.Listing], error)
+	Count(context.Context, data.ListParams) (int64, error)
+	Create(context.Context, *model.Listing) error
+	Update(context.Context, *model.Listing) error
+	Delete(context.Context, ListingKey) error
+	DeleteEntity(context.Context, *model.Listing) error
+	FindByKeys(context.Context, []ListingKey) ([]*model.Listing, error)
+	CreateMany(context.Context, []*model.Listing) ([]error, error)
+	UpdateMany(context.Context, []*model.Listing) error
+	DeleteByKeys(context.Context, []ListingKey) error
+	AssociateCategories(ctx context.Context, key ListingKey, add []string, remove []string) error
+	FilterKeys(ctx context.Context, keys []ListingKey) ([]ListingKey, error)
+	ListByCategory(ctx context.Context, key string, params data.ListParams) (*data.List[*model.Listing], error)
 }
@@ -30,6 +30,11 @@ type Crud struct {
 	Operations *gen.Set[Operation]
 }
 
+// HasOperation returns true if the operations include the operation.
+func (c Crud) HasOperation(op Operation) bool {
+	return c.Operations.Intersect(gen.NewFrozenSet(op)).Len() > 0
+}
+
 // Search defines a type used for implementing search-related logic and behavior in the system.
 type Search struct {
 }
@@ -42,6 +47,8 @@ type SearchPredicateEntry struct {
 	Name      string
 	Label     string
 	Operators []data.SearchOperator
+	// Column is the column the predicate is applied to. Defaults to the name of the predicate.
+	Column string
 }
 
 // Generate returns a jen.Statement representing a slice of SearchPredicateDescriptor instances.
@@ -92,6 +99,28 @@ type Associate struct {
 type FilterKeys struct {
 }
 
+// SoftDelete marks an entity as soft deleted, where deletes mark the entity as deleted rather than removing it, and
+// adds Restore and Purge operations. Requires a Crud implementation.
+type SoftDelete struct {
+}
+
+// Audited marks an entity whose table records when and by whom each entity was created and last updated, in the
+// created_at, updated_at, created_by and updated_by columns. The timestamp columns should be NOT NULL, and the actor
+// columns hold the actor of the context. Requires a Crud implementation.
+type Audited struct {
+}
+
+// History marks an entity whose creates, updates and deletes are recorded in a <table>_history table, and adds the
+// ListHistory operation. Restores and purges of soft deleted entities are not recorded. Requires a Crud
+// implementation.
+type History struct {
+}
+
+// Outbox marks an entity whose creates, updates and deletes append an event to the outbox table, to be published by
+// an outbox relay. The entity type of the events is the name of the entity. Requires a Crud implementation.
+type Outbox struct {
+}
+
 // ListByAssociatedKey specifies a type associated with another entity for relation-based operations or queries.
 type ListByAssociatedKey struct {
 	AssociatedType reflect.Type
@@ -131,7 +160,8 @@ func addBaseHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 }
 
 // addCrudHandlers adds CRUD operation handlers to the given HandlerEntries if the InterfaceMethods has a Crud implementation.
-// It registers handlers for operations such as FindByKey, ExistsByKey, ListAll, Create, Update, Delete, and DeleteEntity.
+// It registers handlers for operations such as FindByKey, ExistsByKey, ListAll, Count, Create, Update, Delete, and DeleteEntity,
+// Upsert, Patch, and FindByKeys, CreateMany, UpdateMany and DeleteByKeys for batch operations.
 func addCrudHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 
 	return he.AddStatementHandler(gen.NewKeyWithTest[*InterfaceMethods](func(in *InterfaceMethods) bool {
@@ -168,6 +198,10 @@ func addCrudHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 						jen.Op("*").Add(jh.StructType),
 					),
 					jen.Error(),
+				)).Add(jen.Id("Count").Params(
+					QualCtx, jen.Qual(ImportThis, "ListParams")).Params(
+					jen.Int64(),
+					jen.Error(),
 				))
 			case OperationCreate:
 				s.Add(jen.Id("Create").Params(
@@ -188,6 +222,36 @@ func addCrudHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 					QualCtx, jen.Op("*").Add(jh.StructType)).Params(
 					jen.Error(),
 				))
+			case OperationUpsert:
+				s.Add(jen.Id("Upsert").Params(
+					QualCtx, jen.Op("*").Add(jh.StructType)).Params(
+					jen.Bool(),
+					jen.Error(),
+				))
+			case OperationPatch:
+				s.Add(jen.Id("Patch").Params(
+					QualCtx, jh.GenerateKeyCode(""), jen.Index().String(), jen.Op("*").Add(jh.StructType)).Params(
+					jen.Error(),
+				))
+			case OperationBatch:
+				s.Add(jen.Id("FindByKeys").Params(
+					QualCtx, jen.Index().Add(jh.GenerateKeyCode(""))).Params(
+					jen.Index().Op("*").Add(jh.StructType),
+					jen.Error(),
+				))
+				s.Add(jen.Id("CreateMany").Params(
+					QualCtx, jen.Index().Op("*").Add(jh.StructType)).Params(
+					jen.Index().Error(),
+					jen.Error(),
+				))
+				s.Add(jen.Id("UpdateMany").Params(
+					QualCtx, jen.Index().Op("*").Add(jh.StructType)).Params(
+					jen.Error(),
+				))
+				s.Add(jen.Id("DeleteByKeys").Params(
+					QualCtx, jen.Index().Add(jh.GenerateKeyCode(""))).Params(
+					jen.Error(),
+				))
 			}
 		}
 
@@ -196,7 +260,8 @@ func addCrudHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 
 }
 
-// addSearchHandlers registers search-related statement handlers into the provided HandlerEntries and returns the updated instance.
+// addSearchHandlers registers search-related statement handlers, for Search, GetSearchPredicates and CountSearch, into the
+// provided HandlerEntries and returns the updated instance.
 func addSearchHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 
 	return he.AddStatementHandler(gen.NewKeyWithTest[*InterfaceMethods](func(in *InterfaceMethods) bool {
@@ -211,9 +276,14 @@ func addSearchHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 			jen.Id("Search").Params(
 				jen.Id("ctx").Add(QualCtx),
 				jen.Id("criteria").Op("[]*").Qual(ImportThis, "SearchPredicate"),
-				jen.Id("params").Op("*").Qual(ImportThis, "PageParams"),
+				jen.Id("params").Qual(ImportThis, "ListParams"),
 			).Params(jen.Op("*").Qual(ImportThis, "List").Types(jen.Op("*").Qual(ImportThis, "SearchResult").Types(jen.Op("*").Add(jh.StructType))), jen.Error())).Add(
-			jen.Id("GetSearchPredicates").Params(QualCtx).Params(jen.Op("[]*").Qual(ImportThis, "SearchPredicateDescriptor"), jen.Error()))
+			jen.Id("GetSearchPredicates").Params(QualCtx).Params(jen.Op("[]*").Qual(ImportThis, "SearchPredicateDescriptor"), jen.Error())).Add(
+			jen.Id("CountSearch").Params(
+				jen.Id("ctx").Add(QualCtx),
+				jen.Id("criteria").Op("[]*").Qual(ImportThis, "SearchPredicate"),
+				jen.Id("params").Qual(ImportThis, "ListParams"),
+			).Params(jen.Int64(), jen.Error()))
 
 	})
 
@@ -312,6 +382,48 @@ func addListByAssociatedKeyHandlers(he *gen.HandlerEntries) *gen.HandlerEntries
 	})
 }
 
+// addSoftDeleteHandlers registers a statement handler generating the Restore and Purge methods for entries with a
+// SoftDelete implementation.
+func addSoftDeleteHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
+
+	return he.AddStatementHandler(gen.NewKeyWithTest[*InterfaceMethods](func(in *InterfaceMethods) bool {
+		return HasImplementation[SoftDelete](in.Entry)
+	}), func(s *jen.Statement, _ gen.Registry, entry any) *jen.Statement {
+
+		i := entry.(*InterfaceMethods)
+
+		jh := i.Entry.GetJenHelper()
+
+		return s.Add(
+			jen.Id("Restore").Params(QualCtx, jh.GenerateKeyCode("")).Params(jen.Error()),
+			jen.Id("Purge").Params(QualCtx, jh.GenerateKeyCode("")).Params(jen.Error()),
+		)
+
+	})
+
+}
+
+// addHistoryHandlers registers a statement handler generating the ListHistory method for entries with a History
+// implementation.
+func addHistoryHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
+
+	return he.AddStatementHandler(gen.NewKeyWithTest[*InterfaceMethods](func(in *InterfaceMethods) bool {
+		return HasImplementation[History](in.Entry)
+	}), func(s *jen.Statement, _ gen.Registry, entry any) *jen.Statement {
+
+		i := entry.(*InterfaceMethods)
+
+		jh := i.Entry.GetJenHelper()
+
+		return s.Add(
+			jen.Id("ListHistory").Params(QualCtx, jh.GenerateKeyCode(""), jen.Qual(ImportThis, "ListParams")).Params(
+				jen.Op("*").Qual(ImportThis, "List").Types(jen.Op("*").Qual(ImportThis, "HistoryEntry")), jen.Error()),
+		)
+
+	})
+
+}
+
 // NewDataRegistry initializes and returns a new genlib.Registry instance with predefined handler entries for various operations.
 func NewDataRegistry() gen.Registry {
 
@@ -322,6 +434,8 @@ func NewDataRegistry() gen.Registry {
 	he = addAssociateHandlers(he)
 	he = addFilterKeysHandlers(he)
 	he = addListByAssociatedKeyHandlers(he)
+	he = addSoftDeleteHandlers(he)
+	he = addHistoryHandlers(he)
 
 	return gen.NewRegistry().WithHandlerEntries(he)
 
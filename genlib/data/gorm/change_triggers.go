@@ -0,0 +1,164 @@
+package gorm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/activatedio/datainfra/genlib/data"
+	"github.com/activatedio/gen"
+)
+
+const (
+	// ChangeChannel is the Postgres notification channel of the change triggers, matching gorm.ChangeChannel
+	ChangeChannel = "datainfra_changes"
+	// ChangeTable is the SQLite table of the change triggers, matching gorm.ChangeTable
+	ChangeTable = "datainfra_changes"
+	// changeFunction is the Postgres trigger function notifying changes
+	changeFunction = "datainfra_notify_change"
+)
+
+// ChangeTriggers marks an entity whose table has change triggers, generated by ChangeTriggersMain, so that writes to
+// it are delivered by a change listener.
+type ChangeTriggers struct {
+	// Version is the version of the migration creating the triggers of the entity, which is not changed once applied.
+	Version int64
+}
+
+// ChangeTriggersMain generates goose migrations creating the change triggers: one with the version of
+// ChangeTriggersMain creating the trigger function and change table, and one with the version of each ChangeTriggers
+// implementation of the entries creating its triggers, so that triggers added later get a new migration. The
+// migrations are templates rendered with a Dialect of "postgres" or "sqlite", such as with fs.TemplateFS. On Postgres,
+// the triggers notify ChangeChannel, and on SQLite, they insert into ChangeTable.
+type ChangeTriggersMain struct {
+	// Version is the version of the migration creating the trigger function and change table.
+	Version int64
+	Entries []data.Entry
+}
+
+// addChangeTriggersHandlers registers the directory handler writing the change triggers migrations.
+func addChangeTriggersHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
+
+	return he.AddDirectoryHandler(gen.NewKey[*ChangeTriggersMain](), func(dirPath string, _ gen.Registry, entry any) {
+
+		m := entry.(*ChangeTriggersMain)
+
+		for name, sql := range changeTriggersSQL(m.Version, m.Entries) {
+			if err := os.WriteFile(filepath.Join(dirPath, name), []byte(sql), 0o644); err != nil {
+				panic(err)
+			}
+		}
+	})
+}
+
+// changeTriggersSQL returns the migrations creating the change triggers by file name: the migration creating the
+// trigger function and change table, and a migration per entry with a ChangeTriggers implementation.
+func changeTriggersSQL(version int64, entries []data.Entry) map[string]string {
+
+	versions := map[int64]string{}
+
+	addVersion := func(v int64, name string) string {
+		if v <= 0 {
+			panic(fmt.Sprintf("change triggers migration %s requires a version", name))
+		}
+		if other, ok := versions[v]; ok {
+			panic(fmt.Sprintf("change triggers migrations %s and %s have the same version %d", other, name, v))
+		}
+		versions[v] = name
+		return fmt.Sprintf("%03d_%s_gen.sql", v, name)
+	}
+
+	files := map[string]string{}
+
+	b := &strings.Builder{}
+
+	b.WriteString("-- Code generated by datainfra. DO NOT EDIT.\n\n-- +goose Up\n\n")
+
+	b.WriteString(`{{ if eq "postgres" .Dialect }}
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ` + changeFunction + `() RETURNS TRIGGER AS $$
+DECLARE
+    row_data JSONB;
+    row_key JSONB := '{}';
+    col TEXT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        row_data := TO_JSONB(OLD);
+    ELSE
+        row_data := TO_JSONB(NEW);
+    END IF;
+    FOREACH col IN ARRAY TG_ARGV LOOP
+        row_key := row_key || JSONB_BUILD_OBJECT(col, row_data -> col);
+    END LOOP;
+    PERFORM PG_NOTIFY('` + ChangeChannel + `', JSONB_BUILD_OBJECT('table', TG_TABLE_NAME, 'key', row_key, 'op', TG_OP)::TEXT);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+{{ else }}
+CREATE TABLE ` + ChangeTable + ` (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    table_name VARCHAR(64) NOT NULL,
+    entity_key TEXT NOT NULL,
+    operation VARCHAR(16) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX ` + ChangeTable + `_changed_at ON ` + ChangeTable + ` (changed_at);
+{{ end }}
+`)
+
+	files[addVersion(version, "change_triggers")] = b.String()
+
+	for _, e := range entries {
+
+		ct := data.GetImplementation[ChangeTriggers](&e)
+		if ct == nil {
+			continue
+		}
+
+		jh := GetGormJenHelper(&e)
+
+		if len(jh.Keys) == 0 {
+			panic(fmt.Sprintf("ChangeTriggers requires a key for %s", jh.StructName))
+		}
+
+		columns := make([]string, len(jh.Keys))
+		for i, k := range jh.Keys {
+			columns[i] = k.Name
+		}
+
+		table := jh.TableName
+
+		b := &strings.Builder{}
+
+		b.WriteString("-- Code generated by datainfra. DO NOT EDIT.\n\n-- +goose Up\n")
+
+		fmt.Fprintf(b, "\n{{ if eq \"postgres\" .Dialect }}\nCREATE TRIGGER %s_change AFTER INSERT OR UPDATE OR DELETE ON %s\n"+
+			"    FOR EACH ROW EXECUTE FUNCTION %s('%s');\n{{ else }}\n", table, table, changeFunction, strings.Join(columns, "', '"))
+
+		for _, op := range []string{"INSERT", "UPDATE", "DELETE"} {
+
+			row := "new"
+			if op == "DELETE" {
+				row = "old"
+			}
+
+			pairs := make([]string, len(columns))
+			for i, c := range columns {
+				pairs[i] = fmt.Sprintf("'%s', %s.%s", c, row, c)
+			}
+
+			fmt.Fprintf(b, "-- +goose StatementBegin\nCREATE TRIGGER %s_change_%s AFTER %s ON %s BEGIN\n"+
+				"    INSERT INTO %s (table_name, entity_key, operation) VALUES ('%s', JSON_OBJECT(%s), '%s');\nEND;\n-- +goose StatementEnd\n",
+				table, strings.ToLower(op), op, table, ChangeTable, table, strings.Join(pairs, ", "), op)
+		}
+
+		b.WriteString("{{ end }}\n")
+
+		files[addVersion(ct.Version, table+"_change_triggers")] = b.String()
+	}
+
+	return files
+}
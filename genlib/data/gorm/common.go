@@ -3,4 +3,12 @@ package gorm
 const (
 	// ImportGorm is the import path for the gorm package
 	ImportGorm = "gorm.io/gorm"
+	// LabelsColumn is the JSON column used to store labels of entities implementing data.WithLabels
+	LabelsColumn = "labels"
+	// SoftDeleteColumn is the nullable timestamp column marking soft deleted entities
+	SoftDeleteColumn = "deleted_at"
+	// AuditTimestampTag is the gorm tag of the audit timestamp fields, hinting a NOT NULL timestamp column
+	AuditTimestampTag = "not null"
+	// AuditActorTag is the gorm tag of the audit actor fields, hinting the size of the actor column
+	AuditActorTag = "size:128"
 )
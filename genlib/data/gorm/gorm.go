@@ -3,6 +3,7 @@ package gorm
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/activatedio/datainfra/genlib/data"
 	"github.com/activatedio/gen"
@@ -80,7 +81,8 @@ type CtorParamsFields struct {
 
 // Ctor represents a constructor wrapper containing a reference to a data entry for further processing or template generation.
 type Ctor struct {
-	Entry *data.Entry
+	Entry           *data.Entry
+	InterfaceImport string
 }
 
 // Search contains predicates for gorm
@@ -128,6 +130,8 @@ func addBaseHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 		opts.Add(
 			jen.Qual(ImportThis, "NewDB"),
 			jen.Qual(ImportThis, "NewContextBuilder"),
+			jen.Qual(ImportThis, "NewTxManager"),
+			jen.Qual(ImportThis, "NewHealthChecker"),
 		)
 
 		for _, d := range im.Entries {
@@ -177,6 +181,7 @@ func addBaseHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 		cpfStmt := &jen.Statement{}
 		// TODO - Add in FX decorators
 		cpfStmt.Add(jen.Qual(data.ImportFX, "In"))
+		cpfStmt.Add(jen.Id("Instrumentation").Qual(ImportThis, "Instrumentation").Tag(map[string]string{"optional": "true"}))
 		cpfStmt.Add(*r.BuildStatement(&jen.Statement{}, &CtorParamsFields{
 			Entry:           d,
 			InterfaceImport: fm.InterfaceImport,
@@ -187,7 +192,8 @@ func addBaseHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 
 		ctor := &jen.Statement{}
 		r.BuildStatement(ctor, &Ctor{
-			Entry: d,
+			Entry:           d,
+			InterfaceImport: fm.InterfaceImport,
 		})
 
 		ctor.Add(jen.Return(jen.Op("&").Qual("", implName).Block(
@@ -197,15 +203,9 @@ func addBaseHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 			})...,
 		)))
 
-		paramsID := "params"
-
-		if len(*cpfStmt) == 1 {
-			paramsID = ""
-		}
-
 		f.Commentf("New%sRepository creates a new %sRepository", jh.StructName, jh.StructName)
 		f.Func().Id(fmt.Sprintf("New%sRepository", jh.StructName)).Params(
-			jen.Id(paramsID).Id(paramsType),
+			jen.Id("params").Id(paramsType),
 		).Qual(fm.InterfaceImport, jh.InterfaceName).Block(*ctor...).Line()
 	}).AddStatementHandler(gen.NewKey[*InternalSuperFields](), func(s *jen.Statement, _ gen.Registry, entry any) *jen.Statement {
 
@@ -226,6 +226,28 @@ func addBaseHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 			tmplStmt.Add(jen.Id("ContextScope").Op(":").Add(jh.ContextScopeCode).Op(","))
 		}
 		tmplStmt.Add(jen.Id("Table").Op(":").Lit(jh.TableName).Op(","))
+		keyColumns := make([]jen.Code, len(jh.Keys))
+		for i, k := range jh.Keys {
+			keyColumns[i] = jen.Lit(k.Name)
+		}
+		tmplStmt.Add(jen.Id("KeyColumns").Op(":").Index().String().Values(keyColumns...).Op(","))
+		if len(jh.SortableColumns) > 0 {
+			sortableFields := make([]jen.Code, len(jh.SortableColumns))
+			for i, c := range jh.SortableColumns {
+				sortableFields[i] = jen.Lit(c)
+			}
+			tmplStmt.Add(jen.Id("SortableFields").Op(":").Index().String().Values(sortableFields...).Op(","))
+		}
+		if jh.HasLabels {
+			tmplStmt.Add(jen.Id("LabelsColumn").Op(":").Lit(LabelsColumn).Op(","))
+		}
+		if data.HasImplementation[data.SoftDelete](d) {
+			tmplStmt.Add(jen.Id("SoftDeleteColumn").Op(":").Lit(SoftDeleteColumn).Op(","))
+		}
+		if data.HasImplementation[data.Audited](d) {
+			tmplStmt.Add(jen.Id("AuditColumns").Op(":").Qual(ImportThis, "DefaultAuditColumns").Call().Op(","))
+		}
+		tmplStmt.Add(jen.Id("Instrumentation").Op(":").Id("params").Dot("Instrumentation").Op(","))
 		tmplStmt.Add(jen.Id("ToInternal").Op(":").Func().Params(
 			jen.Id("m").Op("*").Add(jh.StructType),
 		).Op("*").Id(internalName).Block(
@@ -269,17 +291,39 @@ func addCrudHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 			return s
 		}
 
-		return s.Add(jen.Qual(data.ImportThis, "CrudTemplate").Types(
+		s.Add(jen.Qual(data.ImportThis, "CrudTemplate").Types(
 			jen.Op("*").Add(jh.StructType),
 			jh.GenerateKeyCode(_if.InterfaceImport),
 		))
 
-	}).AddStatementHandler(gen.NewKeyWithTest[*ImplFieldAssignments](func(in *ImplFieldAssignments) bool {
+		if c.HasOperation(data.OperationBatch) {
+			s.Add(jen.Qual(data.ImportThis, "BatchTemplate").Types(
+				jen.Op("*").Add(jh.StructType),
+				jh.GenerateKeyCode(_if.InterfaceImport),
+			))
+		}
+
+		if c.HasOperation(data.OperationUpsert) {
+			s.Add(jen.Qual(data.ImportThis, "UpsertTemplate").Types(
+				jen.Op("*").Add(jh.StructType),
+			))
+		}
+
+		if c.HasOperation(data.OperationPatch) {
+			s.Add(jen.Qual(data.ImportThis, "PatchTemplate").Types(
+				jen.Op("*").Add(jh.StructType),
+				jh.GenerateKeyCode(_if.InterfaceImport),
+			))
+		}
+
+		return s
+
+	}).AddStatementHandler(gen.NewKeyWithTest[*Ctor](func(in *Ctor) bool {
 		return data.HasImplementation[data.Crud](in.Entry)
 	}), func(s *jen.Statement, r gen.Registry, entry any) *jen.Statement {
 
-		_if := entry.(*ImplFieldAssignments)
-		d := _if.Entry
+		ctor := entry.(*Ctor)
+		d := ctor.Entry
 		jh := GetGormJenHelper(d)
 
 		c := data.GetImplementation[data.Crud](d)
@@ -291,31 +335,130 @@ func addCrudHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 		}
 
 		internalName := jh.StructName + "Internal"
+		batch := c.HasOperation(data.OperationBatch)
+		upsert := c.HasOperation(data.OperationUpsert)
 
 		crudParamsFields := r.BuildStatement(&jen.Statement{}, &CrudTemplateParamsField{})
 
-		switch {
+		if fb := findBuilderCode(jh, i, ctor.InterfaceImport); fb != nil {
+			crudParamsFields.Add(jen.Id("FindBuilder").Op(":").Add(fb).Op(","))
+		}
+
+		if fkb := findKeysBuilderCode(jh, i, ctor.InterfaceImport); batch && fkb != nil {
+			crudParamsFields.Add(jen.Line().Id("FindKeysBuilder").Op(":").Add(fkb).Op(","))
+		}
+
+		if jh.VersionColumn != "" {
+			crudParamsFields.Add(jen.Line().Id("VersionColumn").Op(":").Lit(jh.VersionColumn).Op(","))
+		}
 
-		case i != nil && i.CustomFindBuilder != nil:
-			crudParamsFields.Add(jen.Id("FindBuilder").Op(":").Add(i.CustomFindBuilder).Op(","))
-		case len(jh.KeyFields) == 1:
-			crudParamsFields.Add(jen.Id("FindBuilder").Op(":").Qual(ImportThis, "SingleFindBuilder").Types(
-				jh.GenerateKeyCode(_if.InterfaceImport)).Params(jen.Lit(fmt.Sprintf("%s.%s", jh.TableName, strcase.ToSnake(jh.KeyFields[0].Name)))).Op(","))
+		if upsert && i != nil && len(i.ConflictColumns) > 0 {
+			columns := make([]jen.Code, len(i.ConflictColumns))
+			for j, col := range i.ConflictColumns {
+				columns[j] = jen.Lit(col)
+			}
+			crudParamsFields.Add(jen.Line().Id("ConflictColumns").Op(":").Index().String().Values(columns...).Op(","))
 		}
 
-		return s.Add(jen.Id("CrudTemplate").Op(":").Qual(ImportThis, "NewMappingCrudTemplate").Types(
-			jen.Op("*").Add(jh.StructType), jen.Op("*").Qual("", internalName), jh.GenerateKeyCode(_if.InterfaceImport),
-		).Params(jen.Qual(ImportThis, "MappingCrudTemplateImplOptions").Types(
-			jen.Op("*").Add(jh.StructType), jen.Op("*").Qual("", internalName), jh.GenerateKeyCode(_if.InterfaceImport),
+		crudParamsFields.Add(changesFieldsCode(d, jh)...)
+
+		// A single instance is the crud, batch, upsert and patch template, so that they share their options
+		return s.Add(jen.Id("crud").Op(":=").Qual(ImportThis, "NewMappingCrudTemplates").Types(
+			jen.Op("*").Add(jh.StructType), jen.Op("*").Qual("", internalName), jh.GenerateKeyCode(ctor.InterfaceImport),
+		).Call(jen.Qual(ImportThis, "MappingCrudTemplateImplOptions").Types(
+			jen.Op("*").Add(jh.StructType), jen.Op("*").Qual("", internalName), jh.GenerateKeyCode(ctor.InterfaceImport),
 		).Block(
 			jen.Id("Template").Op(":").Id("template").Op(","),
 			crudParamsFields,
-		)).Op(","))
+		)))
+
+	}).AddStatementHandler(gen.NewKeyWithTest[*ImplFieldAssignments](func(in *ImplFieldAssignments) bool {
+		return data.HasImplementation[data.Crud](in.Entry)
+	}), func(s *jen.Statement, _ gen.Registry, entry any) *jen.Statement {
+
+		d := entry.(*ImplFieldAssignments).Entry
+		c := data.GetImplementation[data.Crud](d)
+
+		if c.Operations.Intersect(data.OperationsCrud).Len() == 0 {
+			// Short circuit
+			return s
+		}
+
+		s.Add(jen.Id("CrudTemplate").Op(":").Id("crud").Op(","))
+
+		if c.HasOperation(data.OperationBatch) {
+			s.Add(jen.Id("BatchTemplate").Op(":").Id("crud").Op(","))
+		}
+
+		if c.HasOperation(data.OperationUpsert) {
+			s.Add(jen.Id("UpsertTemplate").Op(":").Id("crud").Op(","))
+		}
+
+		if c.HasOperation(data.OperationPatch) {
+			s.Add(jen.Id("PatchTemplate").Op(":").Id("crud").Op(","))
+		}
+
+		return s
 
 	})
 
 }
 
+// findBuilderCode returns the code of the find builder for the entry, which is the custom find builder if set, a
+// single column find builder for a single key, or a composite find builder for multiple keys. Returns nil if the
+// entry has no keys.
+func findBuilderCode(jh JenHelper, i *Implementation, interfaceImport string) jen.Code {
+	switch {
+	case i != nil && i.CustomFindBuilder != nil:
+		return i.CustomFindBuilder
+	case len(jh.KeyFields) == 1:
+		return jen.Qual(ImportThis, "SingleFindBuilder").Types(
+			jh.GenerateKeyCode(interfaceImport)).Params(keyColumnLits(jh, jh.TableName+".%s")...)
+	case len(jh.KeyFields) > 1:
+		return jen.Qual(ImportThis, "CompositeFindBuilder").Types(
+			jh.GenerateKeyCode(interfaceImport)).Params(keyColumnLits(jh, jh.TableName+".%s")...)
+	default:
+		return nil
+	}
+}
+
+// findKeysBuilderCode returns the code of the find keys builder for the entry, which is a single column or composite
+// find keys builder. Returns nil for a custom find builder, where batch operations find entities one at a time.
+func findKeysBuilderCode(jh JenHelper, i *Implementation, interfaceImport string) jen.Code {
+	switch {
+	case i != nil && i.CustomFindBuilder != nil:
+		return nil
+	case len(jh.KeyFields) == 1:
+		return jen.Qual(ImportThis, "SingleFindKeysBuilder").Types(
+			jh.GenerateKeyCode(interfaceImport)).Params(keyColumnLits(jh, jh.TableName+".%s")...)
+	case len(jh.KeyFields) > 1:
+		return jen.Qual(ImportThis, "CompositeFindKeysBuilder").Types(
+			jh.GenerateKeyCode(interfaceImport)).Params(keyColumnLits(jh, jh.TableName+".%s")...)
+	default:
+		return nil
+	}
+}
+
+// keyColumnLits returns the literals of the key columns of the entry, in the order of the key fields, each formatted
+// with the format.
+func keyColumnLits(jh JenHelper, format string) []jen.Code {
+	res := make([]jen.Code, len(jh.Keys))
+	for i, k := range jh.Keys {
+		res[i] = jen.Lit(fmt.Sprintf(format, k.Name))
+	}
+	return res
+}
+
+// keyColumnsField returns the code of the option field for the key columns of the entry, which is the singular field
+// with a single key, or the plural field with the columns of a composite key.
+func keyColumnsField(jh JenHelper, singular string, plural string, format string) jen.Code {
+	lits := keyColumnLits(jh, format)
+	if len(lits) == 1 {
+		return jen.Id(singular).Op(":").Add(lits[0]).Op(",")
+	}
+	return jen.Id(plural).Op(":").Index().String().Values(lits...).Op(",")
+}
+
 // addSearchHandlers registers statement handlers for search implementations in handler entries and returns the updated instance.
 func addSearchHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 
@@ -350,15 +493,26 @@ func addSearchHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 			predicates = gs.Predicates.Generate()
 		}
 
+		paramsStmt := &jen.Statement{}
+		paramsStmt.Add(jen.Id("Template").Op(":").Id("template").Op(","))
+		paramsStmt.Add(jen.Id("SearchPredicates").Op(":").Add(predicates).Op(","))
+
+		columns := jen.Dict{}
+		for _, p := range gs.Predicates {
+			if p.Column != "" {
+				columns[jen.Lit(p.Name)] = jen.Lit(p.Column)
+			}
+		}
+		if len(columns) > 0 {
+			paramsStmt.Add(jen.Id("PredicateColumns").Op(":").Map(jen.String()).String().Values(columns).Op(","))
+		}
+
 		internalName := jh.StructName + "Internal"
 		return s.Add(jen.Id("SearchTemplate").Op(":").Qual(ImportThis, "NewMappingSearchTemplate").Types(
 			jen.Op("*").Add(jh.StructType), jen.Op("*").Qual("", internalName),
 		).Params(jen.Qual(ImportThis, "MappingSearchTemplateParams").Types(
 			jen.Op("*").Add(jh.StructType), jen.Op("*").Qual("", internalName),
-		).Block(
-			jen.Id("Template").Op(":").Id("template").Op(","),
-			jen.Id("SearchPredicates").Op(":").Add(predicates).Op(","),
-		)).Op(","))
+		).Block(*paramsStmt...)).Op(","))
 
 	})
 
@@ -437,8 +591,8 @@ func addAssociateHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 		fm := entry.(*FileMain)
 		for _, h := range toHelper(fm.Entry) {
 
-			kc := h.parentHelper.GenerateKeyCode("")
-			ckc := h.childHelper.GenerateKeyCode("")
+			kc := h.parentHelper.GenerateKeyCode(fm.InterfaceImport)
+			ckc := h.childHelper.GenerateKeyCode(fm.InterfaceImport)
 
 			implName := strcase.ToLowerCamel(h.parentHelper.StructName) + "RepositoryImpl"
 			receiverID := func() *jen.Statement { return jen.Id("r") }
@@ -447,28 +601,20 @@ func addAssociateHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 			removeID := func() *jen.Statement { return jen.Id("remove") }
 			ctxID := func() *jen.Statement { return jen.Id("ctx") }
 
-			if len(h.parentHelper.Keys) != 1 {
-				panic(fmt.Sprintf("Associate only supports a single key, found %d", len(h.parentHelper.Keys)))
-			}
-			if len(h.childHelper.Keys) != 1 {
-				panic(fmt.Sprintf("Associate only supports a single key, found %d", len(h.childHelper.Keys)))
-			}
-
 			f.Func().Params(receiverID().Op("*").Id(implName)).Id(
 				fmt.Sprintf("Associate%s", pl.Plural(h.childHelper.StructName))).Params(ctxID().Add(data.QualCtx), keyID().Add(kc), addID().Index().Add(ckc), removeID().Index().Add(ckc)).
 				Params(jen.Error()).
 				Block(jen.Return(
 					jen.Qual(ImportThis, "Associate").Types(kc, ckc).Call(ctxID(), jen.Qual(ImportThis, "AssociateParams").Types(kc, ckc).Block(
 						jen.Id("AssociationTable").Op(":").Lit(fmt.Sprintf("%s_%s", h.parentHelper.TablePrefix, h.childHelper.TableName)).Op(","),
-						jen.Id("ParentColumnName").Op(":").Lit(fmt.Sprintf("%s_%s", h.parentHelper.TablePrefix,
-							h.parentHelper.Keys[0].Name)).Op(","),
-						jen.Id("ChildColumnName").Op(":").Lit(fmt.Sprintf("%s_%s", h.childHelper.TablePrefix,
-							h.childHelper.Keys[0].Name)).Op(","),
+						keyColumnsField(h.parentHelper, "ParentColumnName", "ParentColumnNames", h.parentHelper.TablePrefix+"_%s"),
+						keyColumnsField(h.childHelper, "ChildColumnName", "ChildColumnNames", h.childHelper.TablePrefix+"_%s"),
 						jen.Id("ParentKey").Op(":").Add(keyID()).Op(","),
 						jen.Id("Add").Op(":").Add(addID()).Op(","),
 						jen.Id("Remove").Op(":").Add(removeID()).Op(","),
 						jen.Id("ParentRepository").Op(":").Add(receiverID()).Op(","),
 						jen.Id("ChildRepository").Op(":").Add(receiverID()).Dot(fmt.Sprintf("%sRepository", strcase.ToLowerCamel(h.childHelper.StructName))).Op(","),
+						jen.Id("Instrumentation").Op(":").Add(receiverID()).Dot("Template").Dot("GetInstrumentation").Call().Op(","),
 					)),
 				))
 		}
@@ -502,16 +648,11 @@ func addFilterKeysHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 		typs := &jen.Statement{}
 		typs.Add(jen.Op("*").Add(jh.StructType), jen.Op("*").Qual("", internalName), jh.GenerateKeyCode(_if.InterfaceImport))
 
-		if len(jh.Keys) != 1 {
-			fmt.Println(jh.Keys)
-			panic(fmt.Sprintf("FilterKeys only supports a single key, found %d", len(jh.Keys)))
-		}
-
 		return s.Add(jen.Id("FilterKeysTemplate").Op(":").Qual(ImportThis, "NewMappingFilterKeysTemplate").Types(*typs...).
 			Params(jen.Qual(ImportThis, "MappingFilterKeysTemplateImplOptions").Types(*typs...).
 				Block(
 					jen.Id("Template").Op(":").Id("template").Op(","),
-					jen.Id("FindColumn").Op(":").Lit(jh.Keys[0].Name).Op(","),
+					keyColumnsField(jh, "FindColumn", "FindColumns", "%s"),
 				)).Op(","))
 
 	})
@@ -519,7 +660,7 @@ func addFilterKeysHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 }
 
 // addListByAssociatedKeyHandlers adds file handlers for ListByAssociatedKey functionality in the provided HandlerEntries.
-// It generates methods to list items by associated keys, ensuring constraints like the presence of a single key.
+// It generates methods to list items by associated keys, joining on each column of single or composite keys.
 func addListByAssociatedKeyHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
 
 	return he.AddFileHandler(gen.NewKeyWithTest[*FileMain](func(in *FileMain) bool {
@@ -538,16 +679,12 @@ func addListByAssociatedKeyHandlers(he *gen.HandlerEntries) *gen.HandlerEntries
 
 			jha := GetGormJenHelper(_e)
 
-			cka := jha.GenerateKeyCode("")
+			cka := jha.GenerateKeyCode(i.InterfaceImport)
 
 			receiverID := func() *jen.Statement { return jen.Id("r") }
 
 			implName := strcase.ToLowerCamel(jh.StructName) + "RepositoryImpl"
 
-			if len(jh.Keys) != 1 || len(jha.Keys) != 1 {
-				panic(fmt.Sprintf("ListByAssociatedKey only supports a single key, found %d and %d", len(jh.Keys), len(jha.Keys)))
-			}
-
 			ctxName := "ctx"
 			keyName := "key"
 			paramsName := "params"
@@ -559,8 +696,24 @@ func addListByAssociatedKeyHandlers(he *gen.HandlerEntries) *gen.HandlerEntries
 			} else {
 				assocatedTable = fmt.Sprintf("%s_%s", jha.TablePrefix, jh.TableName)
 			}
-			thisAssocatedKey := fmt.Sprintf("%s_%s", jh.TablePrefix, jh.Keys[0].Name)
-			otherAssocatedKey := fmt.Sprintf("%s_%s", jha.TablePrefix, jha.Keys[0].Name)
+
+			joins := make([]string, len(jh.Keys))
+			for j, k := range jh.Keys {
+				joins[j] = fmt.Sprintf("%s.%s_%s = %s.%s", assocatedTable, jh.TablePrefix, k.Name, thisTable, k.Name)
+			}
+
+			// The fields of a composite associated key are matched, in order, to its association columns
+			wheres := make([]string, len(jha.Keys))
+			whereArgs := []jen.Code{nil}
+			for j, k := range jha.Keys {
+				wheres[j] = fmt.Sprintf("%s.%s_%s=?", assocatedTable, jha.TablePrefix, k.Name)
+				if len(jha.Keys) == 1 {
+					whereArgs = append(whereArgs, jen.Id(keyName))
+				} else {
+					whereArgs = append(whereArgs, jen.Id(keyName).Dot(jha.KeyFields[j].Name))
+				}
+			}
+			whereArgs[0] = jen.Lit(strings.Join(wheres, " AND "))
 
 			f.Func().Params(receiverID().Op("*").Id(implName)).Id(fmt.Sprintf("ListBy%s", jha.StructName)).Params(
 				jen.Id(ctxName).Add(data.QualCtx),
@@ -577,21 +730,9 @@ func addListByAssociatedKeyHandlers(he *gen.HandlerEntries) *gen.HandlerEntries
 					jen.Func().Params(
 						jen.Id(txName).Op("*").Qual(ImportGorm, "DB")).Params(jen.Op("*").Qual(ImportGorm, "DB")).Block(
 						jen.Return(jen.Id(txName).Dot("Joins").Call(jen.Lit(
-							fmt.Sprintf("INNER JOIN %s ON %s.%s = %s.%s",
-								assocatedTable,
-								assocatedTable,
-								thisAssocatedKey,
-								thisTable,
-								jh.Keys[0].Name,
-							),
+							fmt.Sprintf("INNER JOIN %s ON %s", assocatedTable, strings.Join(joins, " AND ")),
 						)).
-							Dot("Where").Call(jen.Lit(
-							fmt.Sprintf("%s.%s=?",
-								assocatedTable, otherAssocatedKey,
-							),
-						),
-							jen.Id(keyName),
-						)),
+							Dot("Where").Call(whereArgs...)),
 					),
 					jen.Id(paramsName),
 				),
@@ -601,6 +742,136 @@ func addListByAssociatedKeyHandlers(he *gen.HandlerEntries) *gen.HandlerEntries
 	})
 }
 
+// addAuditedHandlers registers a handler adding the audit fields to the internal struct of entries with an Audited
+// implementation, with gorm tags hinting the types of their columns.
+func addAuditedHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
+
+	return he.AddStatementHandler(gen.NewKeyWithTest[*InternalFields](func(in *InternalFields) bool {
+		return data.HasImplementation[data.Audited](in.Entry)
+	}), func(s *jen.Statement, _ gen.Registry, _ any) *jen.Statement {
+
+		return s.Add(
+			jen.Id("CreatedAt").Qual("time", "Time").Tag(map[string]string{"gorm": AuditTimestampTag}),
+			jen.Id("UpdatedAt").Qual("time", "Time").Tag(map[string]string{"gorm": AuditTimestampTag}),
+			jen.Id("CreatedBy").String().Tag(map[string]string{"gorm": AuditActorTag}),
+			jen.Id("UpdatedBy").String().Tag(map[string]string{"gorm": AuditActorTag}),
+		)
+
+	})
+}
+
+// addSoftDeleteHandlers registers handlers for entries with a SoftDelete implementation. The internal struct gets the
+// soft delete column, and the implementation gets a soft delete template providing Restore and Purge.
+func addSoftDeleteHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
+
+	return he.AddStatementHandler(gen.NewKeyWithTest[*InternalFields](func(in *InternalFields) bool {
+		return data.HasImplementation[data.SoftDelete](in.Entry)
+	}), func(s *jen.Statement, _ gen.Registry, _ any) *jen.Statement {
+
+		return s.Add(jen.Id("DeletedAt").Op("*").Qual("time", "Time"))
+
+	}).AddStatementHandler(gen.NewKeyWithTest[*ImplFields](func(in *ImplFields) bool {
+		return data.HasImplementation[data.SoftDelete](in.Entry)
+	}), func(s *jen.Statement, _ gen.Registry, entry any) *jen.Statement {
+
+		_if := entry.(*ImplFields)
+		jh := _if.Entry.GetJenHelper()
+
+		return s.Add(jen.Qual(data.ImportThis, "SoftDeleteTemplate").Types(jh.GenerateKeyCode(_if.InterfaceImport)))
+
+	}).AddStatementHandler(gen.NewKeyWithTest[*ImplFieldAssignments](func(in *ImplFieldAssignments) bool {
+		return data.HasImplementation[data.SoftDelete](in.Entry)
+	}), func(s *jen.Statement, _ gen.Registry, entry any) *jen.Statement {
+
+		_if := entry.(*ImplFieldAssignments)
+		d := _if.Entry
+		jh := GetGormJenHelper(d)
+
+		fb := findBuilderCode(jh, data.GetImplementation[Implementation](d), _if.InterfaceImport)
+		if fb == nil {
+			panic(fmt.Sprintf("SoftDelete requires a key or a custom find builder, found %d keys", len(jh.Keys)))
+		}
+
+		internalName := jh.StructName + "Internal"
+
+		typs := &jen.Statement{}
+		typs.Add(jen.Op("*").Add(jh.StructType), jen.Op("*").Qual("", internalName), jh.GenerateKeyCode(_if.InterfaceImport))
+
+		return s.Add(jen.Id("SoftDeleteTemplate").Op(":").Qual(ImportThis, "NewMappingSoftDeleteTemplate").Types(*typs...).
+			Params(jen.Qual(ImportThis, "MappingSoftDeleteTemplateImplOptions").Types(*typs...).
+				Block(
+					jen.Id("Template").Op(":").Id("template").Op(","),
+					jen.Id("FindBuilder").Op(":").Add(fb).Op(",").Add(changesFieldsCode(d, jh)...),
+				)).Op(","))
+
+	})
+}
+
+// changesFieldsCode returns the fields of the template options recording the changes of the entity in its history
+// and outbox tables, if any.
+func changesFieldsCode(d *data.Entry, jh JenHelper) []jen.Code {
+
+	var fields []jen.Code
+
+	if data.HasImplementation[data.History](d) {
+		fields = append(fields, jen.Line().Id("HistoryTable").Op(":").Lit(historyTableName(jh)).Op(","))
+	}
+
+	if data.HasImplementation[data.Outbox](d) {
+		fields = append(fields, jen.Line().Id("OutboxTable").Op(":").Qual(ImportThis, "DefaultOutboxTable").Op(","),
+			jen.Line().Id("EntityType").Op(":").Lit(jh.StructName).Op(","))
+	}
+
+	return fields
+}
+
+// historyTableName returns the name of the table recording the history of the entity.
+func historyTableName(jh JenHelper) string {
+	return jh.TableName + "_history"
+}
+
+// addHistoryHandlers registers handlers for entries with a History implementation. The crud templates record changes
+// in the history table, and the implementation gets a history template providing ListHistory.
+func addHistoryHandlers(he *gen.HandlerEntries) *gen.HandlerEntries {
+
+	return he.AddStatementHandler(gen.NewKeyWithTest[*ImplFields](func(in *ImplFields) bool {
+		return data.HasImplementation[data.History](in.Entry)
+	}), func(s *jen.Statement, _ gen.Registry, entry any) *jen.Statement {
+
+		_if := entry.(*ImplFields)
+		jh := _if.Entry.GetJenHelper()
+
+		return s.Add(jen.Qual(data.ImportThis, "HistoryTemplate").Types(jh.GenerateKeyCode(_if.InterfaceImport)))
+
+	}).AddStatementHandler(gen.NewKeyWithTest[*ImplFieldAssignments](func(in *ImplFieldAssignments) bool {
+		return data.HasImplementation[data.History](in.Entry)
+	}), func(s *jen.Statement, _ gen.Registry, entry any) *jen.Statement {
+
+		_if := entry.(*ImplFieldAssignments)
+		d := _if.Entry
+		jh := GetGormJenHelper(d)
+
+		fb := findBuilderCode(jh, data.GetImplementation[Implementation](d), _if.InterfaceImport)
+		if fb == nil {
+			panic(fmt.Sprintf("History requires a key or a custom find builder, found %d keys", len(jh.Keys)))
+		}
+
+		internalName := jh.StructName + "Internal"
+
+		typs := &jen.Statement{}
+		typs.Add(jen.Op("*").Add(jh.StructType), jen.Op("*").Qual("", internalName), jh.GenerateKeyCode(_if.InterfaceImport))
+
+		return s.Add(jen.Id("HistoryTemplate").Op(":").Qual(ImportThis, "NewMappingHistoryTemplate").Types(*typs...).
+			Params(jen.Qual(ImportThis, "MappingCrudTemplateImplOptions").Types(*typs...).
+				Block(
+					jen.Id("Template").Op(":").Id("template").Op(","),
+					jen.Id("FindBuilder").Op(":").Add(fb).Op(","),
+					jen.Id("HistoryTable").Op(":").Lit(historyTableName(jh)).Op(","),
+				)).Op(","))
+
+	})
+}
+
 // NewDataRegistry initializes a new genlib.Registry with predefined sets of handler entries for various operations.
 func NewDataRegistry() gen.Registry {
 
@@ -612,6 +883,10 @@ func NewDataRegistry() gen.Registry {
 	he = addAssociateHandlers(he)
 	he = addFilterKeysHandlers(he)
 	he = addListByAssociatedKeyHandlers(he)
+	he = addSoftDeleteHandlers(he)
+	he = addAuditedHandlers(he)
+	he = addHistoryHandlers(he)
+	he = addChangeTriggersHandlers(he)
 
 	return gen.NewRegistry().WithHandlerEntries(he)
 }
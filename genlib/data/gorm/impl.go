@@ -8,4 +8,7 @@ type Implementation struct {
 	TableName         string
 	ContextScopeCode  jen.Code
 	CustomFindBuilder jen.Code
+	// ConflictColumns are the columns of the unique constraint used as the conflict target of upserts, when it
+	// differs from the key
+	ConflictColumns []string
 }
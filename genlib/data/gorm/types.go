@@ -24,6 +24,8 @@ type JenHelper struct {
 	TablePrefix      string
 	TableName        string
 	Keys             []Key
+	SortableColumns  []string
+	VersionColumn    string
 	ContextScopeCode jen.Code
 }
 
@@ -41,6 +43,18 @@ func GetGormJenHelper(entry *data.Entry) JenHelper {
 		}
 	}
 
+	sortableColumns := make([]string, len(jh.SortableFields))
+
+	for i, f := range jh.SortableFields {
+		sortableColumns[i] = strcase.ToSnake(f.Name)
+	}
+
+	var versionColumn string
+
+	if jh.VersionField != nil {
+		versionColumn = strcase.ToSnake(jh.VersionField.Name)
+	}
+
 	tableName := pl.Plural(strcase.ToSnake(jh.StructName))
 	var csc jen.Code
 
@@ -55,6 +69,8 @@ func GetGormJenHelper(entry *data.Entry) JenHelper {
 	return JenHelper{
 		JenHelper:        jh,
 		Keys:             keys,
+		SortableColumns:  sortableColumns,
+		VersionColumn:    versionColumn,
 		TablePrefix:      strcase.ToSnake(jh.StructName),
 		TableName:        tableName,
 		ContextScopeCode: csc,
@@ -56,7 +56,7 @@ func GetImplementations[I any](e *Entry, opts ...ImplementationOption[I]) []I {
 	return results
 }
 
-// HasImplementation returns true if the entry has an implementation of the given type
+// HasImplementation returns true if the entry has one or more implementations of the given type
 func HasImplementation[I any](e *Entry) bool {
-	return GetImplementation[I](e) != nil
+	return len(GetImplementations[I](e)) > 0
 }
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/activatedio/datainfra/pkg/data"
 	"github.com/activatedio/gen"
 	"github.com/dave/jennifer/jen"
 )
@@ -17,14 +18,32 @@ type Entry struct {
 }
 
 func buildKeys(target *[]reflect.StructField, t reflect.Type) {
+	buildFields(target, t, func(dt Tag) bool {
+		return dt.IsKey
+	})
+}
+
+func buildSortableFields(target *[]reflect.StructField, t reflect.Type) {
+	buildFields(target, t, func(dt Tag) bool {
+		return dt.IsSortable
+	})
+}
+
+func buildVersionFields(target *[]reflect.StructField, t reflect.Type) {
+	buildFields(target, t, func(dt Tag) bool {
+		return dt.IsVersion
+	})
+}
+
+func buildFields(target *[]reflect.StructField, t reflect.Type, include func(dt Tag) bool) {
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
 		dt := ParseTag(f.Tag.Get("data"))
-		if dt.IsKey {
+		if include(dt) {
 			*target = append(*target, f)
 		}
 		if f.Anonymous && f.Type.Kind() == reflect.Struct {
-			buildKeys(target, f.Type)
+			buildFields(target, f.Type, include)
 		}
 	}
 }
@@ -38,8 +57,20 @@ func (e Entry) GetJenHelper() JenHelper {
 	}
 
 	res.StructType = jen.Qual(e.Type.PkgPath(), e.Type.Name())
+	res.HasLabels = reflect.PointerTo(e.Type).Implements(reflect.TypeFor[data.WithLabels]())
 
 	buildKeys(&res.KeyFields, e.Type)
+	buildSortableFields(&res.SortableFields, e.Type)
+
+	var versionFields []reflect.StructField
+	buildVersionFields(&versionFields, e.Type)
+
+	switch {
+	case len(versionFields) == 1:
+		res.VersionField = &versionFields[0]
+	case len(versionFields) > 1:
+		panic("multiple version fields set for " + e.Type.Name())
+	}
 
 	switch {
 	case len(res.KeyFields) == 0:
@@ -56,32 +87,41 @@ func (e Entry) GetJenHelper() JenHelper {
 
 		fs := &jen.Statement{}
 
-		fs.Add(jen.Commentf("%s is the key for %s", keyTypeName, e.Type.Name()))
-
 		for _, key := range res.KeyFields {
 			fs.Add(jen.Id(key.Name).Qual(key.Type.PkgPath(), key.Type.Name()))
 		}
 
-		res.keyStmt = jen.Type().Id(keyTypeName).Struct(*fs...)
+		res.keyStmt = jen.Line().Commentf("%s is the key for %s", keyTypeName, e.Type.Name()).Line().
+			Type().Id(keyTypeName).Struct(*fs...)
 	}
 
 	return res
 }
 
-// Tag represents metadata information, where IsKey indicates whether the tag is a key.
+// Tag represents metadata information, where IsKey indicates whether the tag is a key, IsSortable indicates
+// whether the field may be used in sort criteria and IsVersion indicates the field is the version used for
+// optimistic locking.
 type Tag struct {
-	IsKey bool
+	IsKey      bool
+	IsSortable bool
+	IsVersion  bool
 }
 
 // ParseTag parses a given tag string and returns a Tag object with its properties set based on the parsed content.
-// If the tag contains "key", the IsKey property of the returned Tag is set to true.
+// If the tag contains "key", the IsKey property of the returned Tag is set to true. If the tag contains "sortable",
+// the IsSortable property is set to true. If the tag contains "version", the IsVersion property is set to true.
 func ParseTag(tag string) Tag {
 
 	t := Tag{}
 
 	for _, v := range strings.Split(tag, ",") {
-		if v == "key" {
+		switch v {
+		case "key":
 			t.IsKey = true
+		case "sortable":
+			t.IsSortable = true
+		case "version":
+			t.IsVersion = true
 		}
 	}
 
@@ -125,8 +165,14 @@ type JenHelper struct {
 	StructType    jen.Code
 	StructName    string
 	KeyFields     []reflect.StructField
-	keyCodeGen    keyCodeGenerator
-	keyStmt       *jen.Statement
+	// SortableFields are the fields tagged as sortable
+	SortableFields []reflect.StructField
+	// VersionField is the field tagged as the version, if any
+	VersionField *reflect.StructField
+	// HasLabels indicates the struct implements data.WithLabels
+	HasLabels  bool
+	keyCodeGen keyCodeGenerator
+	keyStmt    *jen.Statement
 }
 
 // GenerateKeyCode generates a key code for the given interface import using the keyCodeGen generator field of JenHelper.
@@ -156,8 +202,18 @@ var (
 	OperationUpdate = Operation{"update"}
 	// OperationDelete defines an operation for deleting items.
 	OperationDelete = Operation{"delete"}
+	// OperationBatch defines operations for finding, creating, updating and deleting many items at once.
+	OperationBatch = Operation{"batch"}
+	// OperationUpsert defines an operation for inserting an item, or updating it if it exists.
+	OperationUpsert = Operation{"upsert"}
+	// OperationPatch defines an operation for updating only the fields of an item in a field mask.
+	OperationPatch = Operation{"patch"}
 	// OperationsCrud represents a frozen set containing all CRUD operations.
 	OperationsCrud = gen.NewFrozenSet(
 		OperationFindByKey, OperationList, OperationCreate, OperationUpdate, OperationDelete,
 	)
+	// OperationsCrudBatch represents a frozen set containing all CRUD operations and the batch operations.
+	OperationsCrudBatch = gen.NewFrozenSet(
+		OperationFindByKey, OperationList, OperationCreate, OperationUpdate, OperationDelete, OperationBatch,
+	)
 )
@@ -5,18 +5,30 @@ import (
 	"testing"
 
 	"github.com/activatedio/datainfra/genlib/data"
+	data2 "github.com/activatedio/datainfra/pkg/data"
 	"github.com/dave/jennifer/jen"
 	"github.com/stretchr/testify/assert"
 )
 
 type Dummy struct {
-	Key string `data:"key"`
+	Key     string `data:"key"`
+	Value   string `data:"sortable"`
+	Version int    `data:"version"`
 }
 
 type Wrapper struct {
 	Dummy
 }
 
+type LabelDummy struct {
+	Key    string `data:"key"`
+	Labels data2.Labels
+}
+
+func (l *LabelDummy) GetLabels() data2.Labels {
+	return l.Labels
+}
+
 func TestEntry_GetJenHelper(t *testing.T) {
 
 	cases := []struct {
@@ -35,6 +47,10 @@ func TestEntry_GetJenHelper(t *testing.T) {
 				assert.Equal(t, "DummyRepository", got.InterfaceName)
 				assert.Equal(t, jen.Qual(reflect.TypeFor[Dummy]().PkgPath(), reflect.TypeFor[Dummy]().Name()), got.StructType)
 				assert.Len(t, got.KeyFields, 1)
+				assert.Len(t, got.SortableFields, 1)
+				if assert.NotNil(t, got.VersionField) {
+					assert.Equal(t, "Version", got.VersionField.Name)
+				}
 			},
 		},
 		{
@@ -48,6 +64,22 @@ func TestEntry_GetJenHelper(t *testing.T) {
 				assert.Equal(t, "WrapperRepository", got.InterfaceName)
 				assert.Equal(t, jen.Qual(reflect.TypeFor[Wrapper]().PkgPath(), reflect.TypeFor[Wrapper]().Name()), got.StructType)
 				assert.Len(t, got.KeyFields, 1)
+				assert.Len(t, got.SortableFields, 1)
+				assert.False(t, got.HasLabels)
+			},
+		},
+		{
+			name: "labels",
+			input: data.Entry{
+				Type: reflect.TypeFor[LabelDummy](),
+			},
+			verify: func(got data.JenHelper) {
+
+				assert.Equal(t, "LabelDummy", got.StructName)
+				assert.Len(t, got.KeyFields, 1)
+				assert.Empty(t, got.SortableFields)
+				assert.Nil(t, got.VersionField)
+				assert.True(t, got.HasLabels)
 			},
 		},
 	}
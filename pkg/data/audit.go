@@ -0,0 +1,17 @@
+package data
+
+import "context"
+
+type actorContextKey struct{}
+
+// WithActor returns a new context in which changes are made by the actor, such as a user or service name, which is
+// recorded on audited entities.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// GetActor returns the actor making changes in the context, or blank if none is set.
+func GetActor(ctx context.Context) string {
+	v, _ := ctx.Value(actorContextKey{}).(string)
+	return v
+}
@@ -0,0 +1,9 @@
+package data
+
+// Change is a write to a row of a table, made by this or another process, as delivered by a change listener.
+type Change struct {
+	Table string
+	// Key holds the values of the key columns of the row, by column.
+	Key map[string]any
+	Op  HistoryOperation
+}
@@ -1,10 +1,192 @@
 package data
 
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound indicates the entity does not exist, or is not visible in the current scope.
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists indicates an entity with the same key or unique value already exists.
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrConflict indicates the operation conflicted with a concurrent operation and may be retried.
+	ErrConflict = errors.New("conflict")
+	// ErrStaleVersion indicates the entity was modified since it was read. A stale version is also a conflict.
+	ErrStaleVersion = errors.New("stale version")
+	// ErrForeignKeyViolation indicates the operation references an entity which does not exist, or removes an
+	// entity which is still referenced.
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	// ErrScopeViolation indicates the operation is not permitted in the current scope.
+	ErrScopeViolation = errors.New("scope violation")
+	// ErrInvalidPredicate indicates a search predicate is not declared or is not valid for its descriptor.
+	ErrInvalidPredicate = errors.New("invalid predicate")
+	// ErrInvalidFieldMask indicates a field mask names a field which is not mapped or may not be updated.
+	ErrInvalidFieldMask = errors.New("invalid field mask")
+)
+
+// withCause appends the message of the cause, if any, to the message.
+func withCause(msg string, cause error) string {
+	if cause == nil {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", msg, cause.Error())
+}
+
 // EntityAlreadyExists represents an error indicating that the entity being created already exists in the repository.
 type EntityAlreadyExists struct {
+	// Cause is the underlying database error, if any.
+	Cause error
 }
 
 // Error returns a string message indicating the entity already exists.
 func (e EntityAlreadyExists) Error() string {
-	return "entity already exists"
+	return withCause("entity already exists", e.Cause)
+}
+
+// Is reports whether the target is EntityAlreadyExists or ErrAlreadyExists.
+func (e EntityAlreadyExists) Is(target error) bool {
+	_, ok := target.(EntityAlreadyExists)
+	return ok || target == ErrAlreadyExists
+}
+
+// Unwrap returns the underlying database error.
+func (e EntityAlreadyExists) Unwrap() error {
+	return e.Cause
+}
+
+// EntityNotFound represents an error indicating that the entity being modified does not exist in the repository,
+// or is not visible in the current scope.
+type EntityNotFound struct {
+}
+
+// Error returns a string message indicating the entity was not found.
+func (e EntityNotFound) Error() string {
+	return "entity not found"
+}
+
+// Is reports whether the target is EntityNotFound or ErrNotFound.
+func (e EntityNotFound) Is(target error) bool {
+	_, ok := target.(EntityNotFound)
+	return ok || target == ErrNotFound
+}
+
+// Conflict represents an error indicating the operation conflicted with a concurrent operation, such as a
+// serialization failure or a deadlock.
+type Conflict struct {
+	// Cause is the underlying database error, if any.
+	Cause error
+}
+
+// Error returns a string message indicating a conflict.
+func (e Conflict) Error() string {
+	return withCause("conflict with a concurrent operation", e.Cause)
+}
+
+// Is reports whether the target is Conflict or ErrConflict.
+func (e Conflict) Is(target error) bool {
+	_, ok := target.(Conflict)
+	return ok || target == ErrConflict
+}
+
+// Unwrap returns the underlying database error.
+func (e Conflict) Unwrap() error {
+	return e.Cause
+}
+
+// StaleVersion represents an error indicating the entity was modified since the version being written was read.
+type StaleVersion struct {
+}
+
+// Error returns a string message indicating a stale version.
+func (e StaleVersion) Error() string {
+	return "entity version is stale"
+}
+
+// Is reports whether the target is StaleVersion, ErrStaleVersion or ErrConflict.
+func (e StaleVersion) Is(target error) bool {
+	_, ok := target.(StaleVersion)
+	return ok || target == ErrStaleVersion || target == ErrConflict
+}
+
+// ForeignKeyViolation represents an error indicating a reference to an entity which does not exist, or the removal
+// of an entity which is still referenced.
+type ForeignKeyViolation struct {
+	// Cause is the underlying database error, if any.
+	Cause error
+}
+
+// Error returns a string message indicating a foreign key violation.
+func (e ForeignKeyViolation) Error() string {
+	return withCause("foreign key violation", e.Cause)
+}
+
+// Is reports whether the target is ForeignKeyViolation or ErrForeignKeyViolation.
+func (e ForeignKeyViolation) Is(target error) bool {
+	_, ok := target.(ForeignKeyViolation)
+	return ok || target == ErrForeignKeyViolation
+}
+
+// Unwrap returns the underlying database error.
+func (e ForeignKeyViolation) Unwrap() error {
+	return e.Cause
+}
+
+// ScopeViolation represents an error indicating the operation is not permitted in the current scope. It is
+// intended to be returned by scope implementations.
+type ScopeViolation struct {
+	// Reason describes the violation.
+	Reason string
+}
+
+// Error returns a string message indicating a scope violation.
+func (e ScopeViolation) Error() string {
+	if e.Reason == "" {
+		return "scope violation"
+	}
+	return fmt.Sprintf("scope violation: %s", e.Reason)
+}
+
+// Is reports whether the target is ScopeViolation or ErrScopeViolation.
+func (e ScopeViolation) Is(target error) bool {
+	_, ok := target.(ScopeViolation)
+	return ok || target == ErrScopeViolation
+}
+
+// InvalidPredicate represents an error indicating a search predicate is not valid.
+type InvalidPredicate struct {
+	// Name is the name of the predicate.
+	Name string
+	// Reason describes why the predicate is not valid.
+	Reason string
+}
+
+// Error returns a string message indicating the predicate is invalid.
+func (e InvalidPredicate) Error() string {
+	return fmt.Sprintf("invalid predicate %s: %s", e.Name, e.Reason)
+}
+
+// Is reports whether the target is InvalidPredicate or ErrInvalidPredicate.
+func (e InvalidPredicate) Is(target error) bool {
+	_, ok := target.(InvalidPredicate)
+	return ok || target == ErrInvalidPredicate
+}
+
+// InvalidFieldMask represents an error indicating a field of a field mask is not valid.
+type InvalidFieldMask struct {
+	// Field is the name of the field in the mask.
+	Field string
+	// Reason describes why the field is not valid.
+	Reason string
+}
+
+// Error returns a string message indicating the field mask is invalid.
+func (e InvalidFieldMask) Error() string {
+	return fmt.Sprintf("invalid field mask %s: %s", e.Field, e.Reason)
+}
+
+// Is reports whether the target is InvalidFieldMask or ErrInvalidFieldMask.
+func (e InvalidFieldMask) Is(target error) bool {
+	_, ok := target.(InvalidFieldMask)
+	return ok || target == ErrInvalidFieldMask
 }
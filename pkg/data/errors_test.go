@@ -0,0 +1,82 @@
+package data_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors_Is(t *testing.T) {
+
+	cause := errors.New("cause")
+
+	cases := map[string]struct {
+		err     error
+		is      []error
+		isNot   []error
+		unwraps bool
+	}{
+		"already exists": {
+			err:     data.EntityAlreadyExists{Cause: cause},
+			is:      []error{data.ErrAlreadyExists, data.EntityAlreadyExists{}},
+			isNot:   []error{data.ErrNotFound, data.ErrConflict},
+			unwraps: true,
+		},
+		"not found": {
+			err:   data.EntityNotFound{},
+			is:    []error{data.ErrNotFound, data.EntityNotFound{}},
+			isNot: []error{data.ErrAlreadyExists},
+		},
+		"conflict": {
+			err:     data.Conflict{Cause: cause},
+			is:      []error{data.ErrConflict, data.Conflict{}},
+			isNot:   []error{data.ErrStaleVersion},
+			unwraps: true,
+		},
+		"stale version": {
+			err:   data.StaleVersion{},
+			is:    []error{data.ErrStaleVersion, data.ErrConflict, data.StaleVersion{}},
+			isNot: []error{data.Conflict{}},
+		},
+		"foreign key violation": {
+			err:     data.ForeignKeyViolation{Cause: cause},
+			is:      []error{data.ErrForeignKeyViolation, data.ForeignKeyViolation{}},
+			isNot:   []error{data.ErrNotFound},
+			unwraps: true,
+		},
+		"scope violation": {
+			err:   data.ScopeViolation{Reason: "tenant"},
+			is:    []error{data.ErrScopeViolation, data.ScopeViolation{}},
+			isNot: []error{data.ErrNotFound},
+		},
+		"invalid predicate": {
+			err:   data.InvalidPredicate{Name: "a", Reason: "b"},
+			is:    []error{data.ErrInvalidPredicate, data.InvalidPredicate{}},
+			isNot: []error{data.ErrNotFound},
+		},
+		"invalid field mask": {
+			err:   data.InvalidFieldMask{Field: "a", Reason: "b"},
+			is:    []error{data.ErrInvalidFieldMask, data.InvalidFieldMask{}},
+			isNot: []error{data.ErrInvalidPredicate},
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+
+			wrapped := fmt.Errorf("wrapped: %w", v.err)
+
+			for _, target := range v.is {
+				assert.ErrorIs(t, v.err, target)
+				assert.ErrorIs(t, wrapped, target)
+			}
+			for _, target := range v.isNot {
+				assert.NotErrorIs(t, v.err, target)
+			}
+			assert.Equal(t, v.unwraps, errors.Is(v.err, cause))
+		})
+	}
+}
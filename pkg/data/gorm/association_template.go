@@ -3,9 +3,9 @@ package gorm
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/activatedio/datainfra/pkg/data"
-	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
@@ -19,18 +19,51 @@ type AssociateParams[PK comparable, CK comparable] struct {
 	AssociationTable string
 	ParentColumnName string
 	ChildColumnName  string
+	// ParentColumnNames optionally replaces ParentColumnName for a composite parent key. The columns are matched, in
+	// order, to the fields of the key struct.
+	ParentColumnNames []string
+	// ChildColumnNames optionally replaces ChildColumnName for a composite child key. The columns are matched, in
+	// order, to the fields of the key struct.
+	ChildColumnNames []string
 	ExecuteRemove    func(ctx context.Context, db *gorm.DB, params AssociateParams[PK, CK], remove []CK) *gorm.DB
-	ExecuteAdd       func(ctx context.Context, db *gorm.DB, params AssociateParams[PK, CK], add CK) *gorm.DB
+	// ExecuteAdd optionally adds a single child. Without it, children are added with multi-row inserts.
+	ExecuteAdd func(ctx context.Context, db *gorm.DB, params AssociateParams[PK, CK], add CK) *gorm.DB
+	// BatchSize is the number of children per insert. Defaults to DefaultBatchSize.
+	BatchSize int
+	// Instrumentation optionally observes the association, as an OperationAssociate on the association table.
+	Instrumentation Instrumentation
 }
 
 // Associate manages the association of a parent entity with child entities, adding or removing as specified in the parameters.
+// Changes are made within a transaction, so either all or none of them are applied.
 func Associate[PK comparable, CK comparable](ctx context.Context, params AssociateParams[PK, CK]) error {
+
+	ctx, end := instrument(ctx, params.Instrumentation, &Operation{
+		Name:      OperationAssociate,
+		Table:     params.AssociationTable,
+		FetchType: data.FetchTypeNone,
+	})
+
+	err := TranslateError(InTx(ctx, func(ctx context.Context) error {
+		return associate(ctx, params)
+	}))
+
+	if err != nil {
+		end(0, err)
+	} else {
+		end(int64(len(params.Add)+len(params.Remove)), nil)
+	}
+
+	return err
+}
+
+func associate[PK comparable, CK comparable](ctx context.Context, params AssociateParams[PK, CK]) error {
 	parentExists, err := params.ParentRepository.ExistsByKey(ctx, params.ParentKey)
 	if err != nil {
 		return err
 	}
 	if !parentExists {
-		return errors.New("parent key not found")
+		return data.EntityNotFound{}
 	}
 
 	filteredAdd, err := params.ChildRepository.FilterKeys(ctx, params.Add)
@@ -50,8 +83,22 @@ func Associate[PK comparable, CK comparable](ctx context.Context, params Associa
 		}
 	}
 
-	for _, childKey := range filteredAdd {
-		if err := executeAdd(ctx, tx, params, childKey); err != nil {
+	if params.ExecuteAdd != nil {
+		for _, childKey := range filteredAdd {
+			if err := params.ExecuteAdd(ctx, tx, params, childKey).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	batchSize := params.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	for _, chunk := range chunks(filteredAdd, batchSize) {
+		if err := executeAdd(tx, params, chunk); err != nil {
 			return err
 		}
 	}
@@ -65,22 +112,73 @@ func isNotEmpty[CK any](keys []CK) bool {
 
 func executeRemove[PK comparable, CK comparable](ctx context.Context, tx *gorm.DB, params AssociateParams[PK, CK], keysToRemove []CK) error {
 	if params.ExecuteRemove != nil {
-		tx = params.ExecuteRemove(ctx, tx, params, keysToRemove)
-	} else {
-		tx = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND %s IN ?",
-			params.AssociationTable, params.ParentColumnName, params.ChildColumnName),
-			params.ParentKey, keysToRemove)
+		return params.ExecuteRemove(ctx, tx, params, keysToRemove).Error
+	}
+
+	parentColumns := params.parentColumns()
+
+	parentValues, err := keyValues(params.ParentKey, len(parentColumns))
+	if err != nil {
+		return err
 	}
-	return tx.Error
+
+	childCond, err := keysInCondition(params.childColumns(), keysToRemove)
+	if err != nil {
+		return err
+	}
+
+	conds := make([]string, len(parentColumns))
+	for i, col := range parentColumns {
+		conds[i] = fmt.Sprintf("%s = ?", col)
+	}
+
+	return tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s AND %s",
+		params.AssociationTable, strings.Join(conds, " AND "), childCond.SQL),
+		append(parentValues, childCond.Vars...)...).Error
 }
 
-func executeAdd[PK comparable, CK comparable](ctx context.Context, tx *gorm.DB, params AssociateParams[PK, CK], keyToAdd CK) error {
-	if params.ExecuteAdd != nil {
-		tx = params.ExecuteAdd(ctx, tx, params, keyToAdd)
-	} else {
-		tx = tx.Exec(fmt.Sprintf("INSERT INTO %s (%s, %s, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)",
-			params.AssociationTable, params.ParentColumnName, params.ChildColumnName),
-			params.ParentKey, keyToAdd)
+func executeAdd[PK comparable, CK comparable](tx *gorm.DB, params AssociateParams[PK, CK], keysToAdd []CK) error {
+
+	parentColumns := params.parentColumns()
+	childColumns := params.childColumns()
+
+	parentValues, err := keyValues(params.ParentKey, len(parentColumns))
+	if err != nil {
+		return err
+	}
+
+	placeholders := strings.Repeat("?, ", len(parentColumns)+len(childColumns))
+
+	rows := make([]string, len(keysToAdd))
+	args := make([]any, 0, len(keysToAdd)*(len(parentColumns)+len(childColumns)))
+
+	for i, k := range keysToAdd {
+		childValues, err := keyValues(k, len(childColumns))
+		if err != nil {
+			return err
+		}
+		rows[i] = fmt.Sprintf("(%sCURRENT_TIMESTAMP)", placeholders)
+		args = append(args, parentValues...)
+		args = append(args, childValues...)
+	}
+
+	return tx.Exec(fmt.Sprintf("INSERT INTO %s (%s, %s, created_at) VALUES %s",
+		params.AssociationTable, strings.Join(parentColumns, ", "), strings.Join(childColumns, ", "), strings.Join(rows, ", ")),
+		args...).Error
+}
+
+// parentColumns returns the association table columns of the parent key.
+func (p AssociateParams[PK, CK]) parentColumns() []string {
+	if len(p.ParentColumnNames) > 0 {
+		return p.ParentColumnNames
+	}
+	return []string{p.ParentColumnName}
+}
+
+// childColumns returns the association table columns of the child key.
+func (p AssociateParams[PK, CK]) childColumns() []string {
+	if len(p.ChildColumnNames) > 0 {
+		return p.ChildColumnNames
 	}
-	return tx.Error
+	return []string{p.ChildColumnName}
 }
@@ -0,0 +1,102 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	"gorm.io/gorm/schema"
+)
+
+// ActorProvider returns the actor making changes in the context, recorded in the audit columns of entities.
+type ActorProvider func(ctx context.Context) string
+
+// ContextActorProvider returns the actor set in the context with data.WithActor.
+func ContextActorProvider(ctx context.Context) string {
+	return data.GetActor(ctx)
+}
+
+// AuditColumns are the columns recording when and by whom an entity was created and last updated. Blank columns are
+// not recorded.
+type AuditColumns struct {
+	CreatedAt string
+	UpdatedAt string
+	CreatedBy string
+	UpdatedBy string
+}
+
+// DefaultAuditColumns returns the conventional audit columns created_at, updated_at, created_by and updated_by.
+func DefaultAuditColumns() *AuditColumns {
+	return &AuditColumns{
+		CreatedAt: "created_at",
+		UpdatedAt: "updated_at",
+		CreatedBy: "created_by",
+		UpdatedBy: "updated_by",
+	}
+}
+
+// CreatedColumns returns the columns which are only set when an entity is created.
+func (a *AuditColumns) CreatedColumns() []string {
+	return nonBlank(a.CreatedAt, a.CreatedBy)
+}
+
+// UpdatedColumns returns the columns which are set whenever an entity is created or updated.
+func (a *AuditColumns) UpdatedColumns() []string {
+	return nonBlank(a.UpdatedAt, a.UpdatedBy)
+}
+
+// IsAuditColumn returns true if the column is one of the audit columns.
+func (a *AuditColumns) IsAuditColumn(column string) bool {
+	for _, c := range append(a.CreatedColumns(), a.UpdatedColumns()...) {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAuditValues sets the audit fields of the internal entity to the time and actor. The created fields are only set
+// if created is true.
+func applyAuditValues(ctx context.Context, sch *schema.Schema, columns *AuditColumns, internal any, now time.Time,
+	actor string, created bool) error {
+
+	values := map[string]any{
+		columns.UpdatedAt: now,
+		columns.UpdatedBy: actor,
+	}
+
+	if created {
+		values[columns.CreatedAt] = now
+		values[columns.CreatedBy] = actor
+	}
+
+	rv := reflect.ValueOf(internal)
+
+	for col, v := range values {
+		if col == "" {
+			continue
+		}
+		f := sch.LookUpField(col)
+		if f == nil {
+			return fmt.Errorf("audit column %s not found in %s", col, sch.Name)
+		}
+		if err := f.Set(ctx, rv, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nonBlank returns the values which are not blank.
+func nonBlank(values ...string) []string {
+	var res []string
+	for _, v := range values {
+		if v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
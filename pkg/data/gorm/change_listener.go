@@ -0,0 +1,415 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+const (
+	// ChangeChannel is the Postgres notification channel to which the change triggers notify changes.
+	ChangeChannel = "datainfra_changes"
+	// ChangeTable is the SQLite table to which the change triggers insert changes.
+	ChangeTable = "datainfra_changes"
+	// DefaultChangePollInterval is the default interval at which the SQLite change table is polled.
+	DefaultChangePollInterval = time.Second
+	// DefaultChangeRetryInterval is the default interval after which listening is first retried once it fails.
+	DefaultChangeRetryInterval = 100 * time.Millisecond
+	// DefaultChangeMaxRetryInterval is the default limit to which the retry interval is doubled.
+	DefaultChangeMaxRetryInterval = 30 * time.Second
+	// DefaultChangeRetention is the default duration for which the rows of the SQLite change table are kept.
+	DefaultChangeRetention = time.Hour
+	// DefaultChangePruneInterval is the default interval at which the SQLite change table is pruned.
+	DefaultChangePruneInterval = time.Minute
+)
+
+// changeRow is a row of the SQLite change table.
+type changeRow struct {
+	ID        int64  `gorm:"column:id;primaryKey;autoIncrement"`
+	TableName string `gorm:"column:table_name"`
+	EntityKey string `gorm:"column:entity_key"`
+	Operation string `gorm:"column:operation"`
+}
+
+// changePayload is the payload of a Postgres change notification.
+type changePayload struct {
+	Table string         `json:"table"`
+	Key   map[string]any `json:"key"`
+	Op    string         `json:"op"`
+}
+
+// ChangeListener delivers the changes to tables with change triggers, written by any process. On Postgres, changes
+// are notified on ChangeChannel with LISTEN/NOTIFY, and the changes made while reconnecting are not delivered. On
+// SQLite, the ChangeTable is polled, and its rows are pruned once older than the retention.
+type ChangeListener interface {
+	// Subscribe starts listening for changes, returning once listening, and delivers the changes made from then on
+	// to the channel until the context is done, when the channel is closed. If listening fails, which is logged, it
+	// is retried with backoff.
+	Subscribe(ctx context.Context) (<-chan *data.Change, error)
+}
+
+// ChangeListenerConfig is the configuration of the change listener.
+type ChangeListenerConfig struct {
+	// PollInterval is the interval at which the SQLite change table is polled. Defaults to DefaultChangePollInterval.
+	PollInterval time.Duration
+	// RetryInterval is the interval after which listening is first retried once it fails, doubled on each failed
+	// retry. Defaults to DefaultChangeRetryInterval.
+	RetryInterval time.Duration
+	// MaxRetryInterval is the limit of the retry interval. Defaults to DefaultChangeMaxRetryInterval.
+	MaxRetryInterval time.Duration
+	// Retention is the duration for which the rows of the SQLite change table are kept. Defaults to
+	// DefaultChangeRetention.
+	Retention time.Duration
+	// PruneInterval is the interval at which the SQLite change table is pruned. Defaults to
+	// DefaultChangePruneInterval.
+	PruneInterval time.Duration
+}
+
+type changeListener struct {
+	db     *gorm.DB
+	config *ChangeListenerConfig
+}
+
+// ChangeListenerParams are the parameters for NewChangeListener.
+type ChangeListenerParams struct {
+	fx.In
+	DB     *gorm.DB
+	Config *ChangeListenerConfig `optional:"true"`
+}
+
+// NewChangeListener creates a ChangeListener for the database, listening with the change listener of its dialect.
+func NewChangeListener(params ChangeListenerParams) ChangeListener {
+
+	config := &ChangeListenerConfig{
+		PollInterval:     DefaultChangePollInterval,
+		RetryInterval:    DefaultChangeRetryInterval,
+		MaxRetryInterval: DefaultChangeMaxRetryInterval,
+		Retention:        DefaultChangeRetention,
+		PruneInterval:    DefaultChangePruneInterval,
+	}
+
+	if c := params.Config; c != nil {
+		if c.PollInterval > 0 {
+			config.PollInterval = c.PollInterval
+		}
+		if c.RetryInterval > 0 {
+			config.RetryInterval = c.RetryInterval
+		}
+		if c.MaxRetryInterval > 0 {
+			config.MaxRetryInterval = c.MaxRetryInterval
+		}
+		if c.Retention > 0 {
+			config.Retention = c.Retention
+		}
+		if c.PruneInterval > 0 {
+			config.PruneInterval = c.PruneInterval
+		}
+	}
+
+	return &changeListener{
+		db:     params.DB,
+		config: config,
+	}
+}
+
+// Subscribe starts listening for changes with the change listener of the dialect of the database.
+func (l *changeListener) Subscribe(ctx context.Context) (<-chan *data.Change, error) {
+
+	dialect, err := dialectOf(l.db)
+	if err != nil {
+		return nil, err
+	}
+
+	listener := dialect.ChangeListener(l.db, l.config)
+	if listener == nil {
+		return nil, fmt.Errorf("change listener not supported for dialect %s", dialect.Name())
+	}
+
+	return listener.Subscribe(ctx)
+}
+
+// pgxConn is implemented by the connections of the pgx database/sql driver.
+type pgxConn interface {
+	Conn() *pgx.Conn
+}
+
+// postgresChangeListener listens for the notifications of changes on ChangeChannel.
+type postgresChangeListener struct {
+	db     *gorm.DB
+	config *ChangeListenerConfig
+}
+
+// Subscribe listens on ChangeChannel with a dedicated connection, which is discarded and replaced if listening fails.
+func (l *postgresChangeListener) Subscribe(ctx context.Context) (<-chan *data.Change, error) {
+
+	conn, pc, err := l.listen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *data.Change)
+
+	go func() {
+
+		defer close(ch)
+
+		retry := newRetryBackoff(l.config)
+
+		for {
+			err := l.receive(ctx, conn, pc, ch)
+			_ = conn.Close()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Warn().Err(err).Msg("change listener")
+
+			for {
+				if !retry.wait(ctx) {
+					return
+				}
+				if conn, pc, err = l.listen(ctx); err == nil {
+					break
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				log.Warn().Err(err).Msg("change listener")
+			}
+
+			retry.reset()
+		}
+	}()
+
+	return ch, nil
+}
+
+// listen takes a connection from the pool listening on ChangeChannel.
+func (l *postgresChangeListener) listen(ctx context.Context) (*sql.Conn, *pgx.Conn, error) {
+
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var pc *pgx.Conn
+
+	if err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(pgxConn)
+		if !ok {
+			return fmt.Errorf("connection %T is not a pgx connection", driverConn)
+		}
+		pc = c.Conn()
+		_, err := pc.Exec(ctx, "LISTEN "+pgx.Identifier{ChangeChannel}.Sanitize())
+		return err
+	}); err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, pc, nil
+}
+
+// receive delivers the notifications of the listening connection to the channel until receiving fails or the context
+// is done, returning the error.
+func (l *postgresChangeListener) receive(ctx context.Context, conn *sql.Conn, pc *pgx.Conn, ch chan<- *data.Change) error {
+
+	var err error
+
+	_ = conn.Raw(func(_ any) error {
+
+		for {
+			var n *pgconn.Notification
+
+			if n, err = pc.WaitForNotification(ctx); err != nil {
+				// The connection is still listening, so it is not returned to the pool
+				return driver.ErrBadConn
+			}
+
+			var p changePayload
+
+			if err := json.Unmarshal([]byte(n.Payload), &p); err != nil {
+				log.Warn().Err(err).Str("payload", n.Payload).Msg("invalid change notification")
+				continue
+			}
+
+			if !deliverChange(ctx, ch, &data.Change{
+				Table: p.Table,
+				Key:   p.Key,
+				Op:    changeOperation(p.Op),
+			}) {
+				err = ctx.Err()
+				return driver.ErrBadConn
+			}
+		}
+	})
+
+	return err
+}
+
+// sqliteChangeListener polls ChangeTable for the changes inserted by the change triggers.
+type sqliteChangeListener struct {
+	db     *gorm.DB
+	config *ChangeListenerConfig
+}
+
+// Subscribe polls ChangeTable for the rows inserted after the last row when subscribing, retrying failed polls with
+// backoff, and prunes the rows older than the retention.
+func (l *sqliteChangeListener) Subscribe(ctx context.Context) (<-chan *data.Change, error) {
+
+	var last int64
+
+	if err := l.db.WithContext(ctx).Table(ChangeTable).Select("COALESCE(MAX(id), 0)").Scan(&last).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+
+	ch := make(chan *data.Change)
+
+	go func() {
+
+		defer close(ch)
+
+		ticker := time.NewTicker(l.config.PollInterval)
+		defer ticker.Stop()
+
+		retry := newRetryBackoff(l.config)
+
+		var pruned time.Time
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if time.Since(pruned) >= l.config.PruneInterval {
+				if err := l.prune(ctx); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					log.Warn().Err(err).Msg("change listener prune")
+				}
+				pruned = time.Now()
+			}
+
+			var rows []*changeRow
+
+			if err := l.db.WithContext(ctx).Table(ChangeTable).Where("id > ?", last).Order("id").Find(&rows).Error; err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Warn().Err(err).Msg("change listener")
+				if !retry.wait(ctx) {
+					return
+				}
+				continue
+			}
+
+			retry.reset()
+
+			for _, r := range rows {
+
+				last = r.ID
+
+				var key map[string]any
+
+				if err := json.Unmarshal([]byte(r.EntityKey), &key); err != nil {
+					log.Warn().Err(err).Int64("id", r.ID).Msg("invalid change row")
+					continue
+				}
+
+				if !deliverChange(ctx, ch, &data.Change{
+					Table: r.TableName,
+					Key:   key,
+					Op:    changeOperation(r.Operation),
+				}) {
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// prune deletes the rows of ChangeTable older than the retention.
+func (l *sqliteChangeListener) prune(ctx context.Context) error {
+	return TranslateError(l.db.WithContext(ctx).Exec("DELETE FROM "+ChangeTable+" WHERE changed_at < DATETIME('now', ?)",
+		fmt.Sprintf("-%d seconds", int64(l.config.Retention/time.Second))).Error)
+}
+
+// retryBackoff is the interval to wait before retrying listening, doubled on each retry up to a limit.
+type retryBackoff struct {
+	min      time.Duration
+	max      time.Duration
+	interval time.Duration
+}
+
+// newRetryBackoff creates a retryBackoff with the retry intervals of the config.
+func newRetryBackoff(config *ChangeListenerConfig) *retryBackoff {
+	return &retryBackoff{
+		min:      config.RetryInterval,
+		max:      config.MaxRetryInterval,
+		interval: config.RetryInterval,
+	}
+}
+
+// wait waits for the interval, then doubles it, returning false if the context is done first.
+func (b *retryBackoff) wait(ctx context.Context) bool {
+
+	timer := time.NewTimer(b.interval)
+	defer timer.Stop()
+
+	b.interval = min(b.interval*2, b.max)
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// reset restores the interval once listening succeeds.
+func (b *retryBackoff) reset() {
+	b.interval = b.min
+}
+
+// deliverChange sends the change to the channel, returning false if the context is done first.
+func deliverChange(ctx context.Context, ch chan<- *data.Change, change *data.Change) bool {
+	select {
+	case ch <- change:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// changeOperation returns the operation of the trigger operation of a change.
+func changeOperation(op string) data.HistoryOperation {
+	switch op {
+	case "INSERT":
+		return data.HistoryOperationCreate
+	case "DELETE":
+		return data.HistoryOperationDelete
+	default:
+		return data.HistoryOperationUpdate
+	}
+}
@@ -0,0 +1,127 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CompositeFindBuilder returns a FindBuilder function that constructs a query to find an entity with a composite key.
+// The fields of the key struct are matched, in order, to the specified columns.
+func CompositeFindBuilder[K comparable](findColumns ...string) FindBuilder[K] {
+	return func(_ context.Context, tx *gorm.DB, key K) *gorm.DB {
+
+		values, err := keyValues(key, len(findColumns))
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		for i, col := range findColumns {
+			tx = tx.Where(fmt.Sprintf("%s = ?", col), values[i])
+		}
+
+		return tx
+	}
+}
+
+// CompositeFindKeysBuilder returns a FindKeysBuilder function that constructs a query to find entities with any of
+// the composite keys. The fields of the key struct are matched, in order, to the specified columns.
+func CompositeFindKeysBuilder[K comparable](findColumns ...string) FindKeysBuilder[K] {
+	return func(_ context.Context, tx *gorm.DB, keys []K) *gorm.DB {
+
+		cond, err := keysInCondition(findColumns, keys)
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		return tx.Where(cond)
+	}
+}
+
+// keyValues returns the values of the key for the number of columns. A single column takes the key itself, while
+// multiple columns take the fields of the key struct in order.
+func keyValues(key any, columns int) ([]any, error) {
+
+	if columns == 1 {
+		return []any{key}, nil
+	}
+
+	rv := reflect.ValueOf(key)
+
+	if rv.Kind() != reflect.Struct || rv.NumField() != columns {
+		return nil, fmt.Errorf("key %T does not have %d fields for a composite key", key, columns)
+	}
+
+	values := make([]any, columns)
+	for i := range values {
+		values[i] = rv.Field(i).Interface()
+	}
+
+	return values, nil
+}
+
+// keysInCondition returns the condition matching any of the keys in the columns, as a tuple IN for multiple columns.
+func keysInCondition[K comparable](columns []string, keys []K) (clause.Expr, error) {
+
+	if len(columns) == 1 {
+		return clause.Expr{
+			SQL:  fmt.Sprintf("%s IN ?", columns[0]),
+			Vars: []any{keys},
+		}, nil
+	}
+
+	tuples := make([][]any, len(keys))
+
+	for i, k := range keys {
+		values, err := keyValues(k, len(columns))
+		if err != nil {
+			return clause.Expr{}, err
+		}
+		tuples[i] = values
+	}
+
+	return clause.Expr{
+		SQL:  fmt.Sprintf("(%s) IN ?", strings.Join(columns, ", ")),
+		Vars: []any{tuples},
+	}, nil
+}
+
+// scanKeys scans the rows of the key columns into keys. A single column is scanned into the key itself, while
+// multiple columns are scanned into the fields of the key struct in order.
+func scanKeys[K comparable](rows *sql.Rows, columns int) ([]K, error) {
+
+	var result []K
+
+	for rows.Next() {
+
+		var k K
+		rv := reflect.ValueOf(&k).Elem()
+
+		dest := []any{&k}
+
+		if columns > 1 {
+			if rv.Kind() != reflect.Struct || rv.NumField() != columns {
+				return nil, fmt.Errorf("key %T does not have %d fields for a composite key", k, columns)
+			}
+			dest = make([]any, columns)
+			for i := range dest {
+				dest[i] = rv.Field(i).Addr().Interface()
+			}
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, k)
+	}
+
+	return result, rows.Err()
+}
@@ -1,5 +1,11 @@
 package gorm
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Config defines the configuration for a gorm database connection.
 type Config struct {
 	Dialect                  string
@@ -10,4 +16,123 @@ type Config struct {
 	Username                 string
 	Password                 string
 	Name                     string
+	// ReplicaHosts are the hosts of read replicas of the database, which are connected to with the port, credentials
+	// and name of the database. Only supported on postgres.
+	ReplicaHosts []string
+	// DSN overrides the DSN built from the config, such as for options without a field. On sqlite, it is the file
+	// name with its query parameters. Replica hosts cannot be used with a DSN.
+	DSN string
+
+	// MaxOpenConns is the maximum number of open connections. Defaults to unlimited on postgres, and 1 on sqlite.
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections. Defaults to the database/sql default.
+	MaxIdleConns int
+	// ConnMaxLifetime is the maximum time a connection is reused. Defaults to no limit.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is the maximum time a connection is idle before it is closed. Defaults to no limit.
+	ConnMaxIdleTime time.Duration
+
+	// ConnectTimeout is the maximum time to wait for a connection to be established, in whole seconds. Postgres only.
+	ConnectTimeout time.Duration
+	// StatementTimeout is the maximum time a statement runs before it is canceled on postgres. On sqlite, it is the
+	// maximum time to wait for a lock, defaulting to 5 seconds.
+	StatementTimeout time.Duration
+
+	// SSLMode is the postgres sslmode, such as disable, require or verify-full. Defaults to the driver default.
+	SSLMode string
+	// SSLRootCert is the file of the certificate authorities verifying the server certificate.
+	SSLRootCert string
+	// SSLCert is the file of the client certificate.
+	SSLCert string
+	// SSLKey is the file of the key of the client certificate.
+	SSLKey string
+
+	// ApplicationName is reported to postgres as the application_name of the connections.
+	ApplicationName string
+	// SearchPath is the postgres search_path of the connections, such as the schema of the application.
+	SearchPath string
+
+	// SlowQueryThreshold is the duration above which queries are logged as slow at warn level. Defaults to
+	// DefaultSlowQueryThreshold, and a negative threshold disables slow query logging. Every query is logged at info
+	// level with EnableSQLLogging.
+	SlowQueryThreshold time.Duration
+	// RedactSQLParameters logs queries with their placeholders rather than the values of their parameters.
+	RedactSQLParameters bool
+}
+
+// WithName returns a copy of the config connecting to the database of the name, such as with the owner credentials of a
+// database being set up. Replica hosts are not copied, since they replicate the database of the config. Returns an
+// error if the config has a DSN, which names its own database.
+func (c *Config) WithName(name string) (*Config, error) {
+
+	if c.DSN != "" {
+		return nil, fmt.Errorf("cannot connect to database %s with the DSN of the config", name)
+	}
+
+	res := *c
+	res.Name = name
+	res.ReplicaHosts = nil
+
+	return &res, nil
+}
+
+// defaultSqliteBusyTimeout is the time sqlite waits for a lock when no statement timeout is configured.
+const defaultSqliteBusyTimeout = 5 * time.Second
+
+// postgresDSN returns the DSN of the postgres database of the config on the host, in the keyword/value format.
+func postgresDSN(config *Config, host string) string {
+
+	var parts []string
+
+	add := func(key string, value string) {
+		if value != "" {
+			parts = append(parts, fmt.Sprintf("%s=%s", key, dsnValue(value)))
+		}
+	}
+
+	add("host", host)
+	if config.Port != 0 {
+		add("port", fmt.Sprint(config.Port))
+	}
+	add("user", config.Username)
+	add("password", config.Password)
+	add("dbname", config.Name)
+	add("sslmode", config.SSLMode)
+	add("sslrootcert", config.SSLRootCert)
+	add("sslcert", config.SSLCert)
+	add("sslkey", config.SSLKey)
+	add("application_name", config.ApplicationName)
+	add("search_path", config.SearchPath)
+
+	if config.ConnectTimeout > 0 {
+		add("connect_timeout", fmt.Sprint(max(1, int(config.ConnectTimeout.Seconds()))))
+	}
+	if config.StatementTimeout > 0 {
+		add("statement_timeout", fmt.Sprint(config.StatementTimeout.Milliseconds()))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// dsnValue quotes the value of a keyword/value DSN if it is empty or contains spaces, quotes or backslashes, escaping
+// the quotes and backslashes.
+func dsnValue(value string) string {
+
+	if value != "" && !strings.ContainsAny(value, " \t\n\r'\\") {
+		return value
+	}
+
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// sqliteDSN returns the DSN of the sqlite database of the config.
+func sqliteDSN(config *Config) string {
+
+	busyTimeout := config.StatementTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = defaultSqliteBusyTimeout
+	}
+
+	return fmt.Sprintf("%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)",
+		config.Name, busyTimeout.Milliseconds())
 }
@@ -0,0 +1,336 @@
+package gorm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
+	"github.com/glebarez/sqlite"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	gorm2 "gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestNewDB_Config(t *testing.T) {
+
+	type s struct {
+		config       *gorm.Config
+		maxOpenConns int
+		busyTimeout  int
+	}
+
+	name := filepath.Join(t.TempDir(), "config")
+
+	cases := map[string]s{
+		"default": {
+			config: &gorm.Config{
+				Dialect: gorm.DialectSqlite,
+				Name:    name,
+			},
+			maxOpenConns: 1,
+			busyTimeout:  5000,
+		},
+		"pool and timeout": {
+			config: &gorm.Config{
+				Dialect:          gorm.DialectSqlite,
+				Name:             name,
+				MaxOpenConns:     4,
+				MaxIdleConns:     2,
+				ConnMaxIdleTime:  time.Minute,
+				StatementTimeout: 2 * time.Second,
+			},
+			maxOpenConns: 4,
+			busyTimeout:  2000,
+		},
+		"dsn": {
+			config: &gorm.Config{
+				Dialect: gorm.DialectSqlite,
+				DSN:     name + "?_pragma=busy_timeout(1234)",
+			},
+			maxOpenConns: 1,
+			busyTimeout:  1234,
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+
+			a := assert.New(t)
+			r := require.New(t)
+
+			db, err := gorm.NewDB(v.config)
+			r.NoError(err)
+
+			sqlDB, err := db.DB()
+			r.NoError(err)
+			defer func() {
+				r.NoError(sqlDB.Close())
+			}()
+
+			a.Equal(v.maxOpenConns, sqlDB.Stats().MaxOpenConnections)
+
+			var busyTimeout int
+			r.NoError(db.Raw("PRAGMA busy_timeout").Scan(&busyTimeout).Error)
+			a.Equal(v.busyTimeout, busyTimeout)
+		})
+	}
+}
+
+func TestNewDB_DSNWithReplicas(t *testing.T) {
+
+	_, err := gorm.NewDB(&gorm.Config{
+		Dialect:      gorm.DialectPostgres,
+		DSN:          "host=localhost",
+		ReplicaHosts: []string{"replica"},
+	})
+
+	assert.EqualError(t, err, "replicas not supported with a DSN")
+}
+
+func TestPostgresDialect_Dialector(t *testing.T) {
+
+	type s struct {
+		password string
+		dsn      string
+	}
+
+	cases := map[string]s{
+		"plain": {
+			password: "secret",
+			dsn:      "host=primary port=5432 user=app password=secret dbname=app",
+		},
+		"quotes": {
+			password: `it's "quoted"`,
+			dsn:      `host=primary port=5432 user=app password='it\'s "quoted"' dbname=app`,
+		},
+		"backslashes": {
+			password: `back\slash\`,
+			dsn:      `host=primary port=5432 user=app password='back\\slash\\' dbname=app`,
+		},
+		"spaces": {
+			password: " with spaces ",
+			dsn:      "host=primary port=5432 user=app password=' with spaces ' dbname=app",
+		},
+		"empty": {
+			password: "",
+			dsn:      "host=primary port=5432 user=app dbname=app",
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+
+			a := assert.New(t)
+			r := require.New(t)
+
+			dialect, err := gorm.LookupDialect(gorm.DialectPostgres)
+			r.NoError(err)
+
+			dialector, err := dialect.Dialector(&gorm.Config{
+				Host:     "primary",
+				Port:     5432,
+				Username: "app",
+				Password: v.password,
+				Name:     "app",
+			})
+			r.NoError(err)
+
+			dsn := dialector.(*postgres.Dialector).DSN
+			a.Equal(v.dsn, dsn)
+
+			// The driver reads back the password
+			parsed, err := pgconn.ParseConfig(dsn)
+			r.NoError(err)
+			a.Equal(v.password, parsed.Password)
+			a.Equal("app", parsed.Database)
+		})
+	}
+}
+
+func TestConfig_WithName(t *testing.T) {
+
+	a := assert.New(t)
+	r := require.New(t)
+
+	owner := &gorm.Config{
+		Dialect:      gorm.DialectPostgres,
+		Host:         "primary",
+		Username:     "owner",
+		Name:         "postgres",
+		ReplicaHosts: []string{"replica"},
+	}
+
+	got, err := owner.WithName("app")
+	r.NoError(err)
+	a.Equal("app", got.Name)
+	a.Equal("owner", got.Username)
+	a.Empty(got.ReplicaHosts)
+	a.Equal("postgres", owner.Name)
+	a.Equal([]string{"replica"}, owner.ReplicaHosts)
+
+	_, err = (&gorm.Config{DSN: "host=primary dbname=postgres"}).WithName("app")
+	a.Error(err)
+}
+
+// memoryDialect is an in-memory variant of the sqlite dialect.
+type memoryDialect struct {
+	gorm.SqliteDialect
+}
+
+func (m *memoryDialect) Name() string {
+	return "sqlite-memory"
+}
+
+func (m *memoryDialect) Dialector(config *gorm.Config) (gorm2.Dialector, error) {
+	return sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", config.Name)), nil
+}
+
+func TestRegisterDialect(t *testing.T) {
+
+	a := assert.New(t)
+	r := require.New(t)
+
+	_, err := gorm.NewDB(&gorm.Config{
+		Dialect: "sqlite-memory",
+		Name:    "dialect",
+	})
+	r.EqualError(err, `unknown dialect "sqlite-memory"`)
+
+	gorm.RegisterDialect(&memoryDialect{})
+	t.Cleanup(func() {
+		gorm.UnregisterDialect("sqlite-memory")
+	})
+
+	dialect, err := gorm.LookupDialect("sqlite-memory")
+	r.NoError(err)
+	a.Equal("sqlite3", dialect.GooseDialect())
+
+	db, err := gorm.NewDB(&gorm.Config{
+		Dialect: "sqlite-memory",
+		Name:    "dialect",
+	})
+	r.NoError(err)
+
+	sqlDB, err := db.DB()
+	r.NoError(err)
+	defer func() {
+		r.NoError(sqlDB.Close())
+	}()
+
+	a.Equal(1, sqlDB.Stats().MaxOpenConnections)
+
+	r.NoError(db.Exec("CREATE TABLE labelled (name VARCHAR(64) PRIMARY KEY, labels TEXT NOT NULL)").Error)
+	r.NoError(db.Exec(`INSERT INTO labelled (name, labels) VALUES ('a', '{"tier": "gold"}'), ('b', '{"tier": "silver"}')`).Error)
+
+	sel, err := labels.Parse("tier=gold")
+	r.NoError(err)
+
+	tx, remaining := gorm.ApplyLabelSelector(db.Table("labelled"), "labels", sel)
+	a.Nil(remaining)
+
+	var names []string
+	r.NoError(tx.Pluck("name", &names).Error)
+	a.Equal([]string{"a"}, names)
+}
+
+func TestNewLogger(t *testing.T) {
+
+	type s struct {
+		config *gorm.Config
+		assert func(a *assert.Assertions, entries []map[string]any)
+	}
+
+	cases := map[string]s{
+		"all queries": {
+			config: &gorm.Config{
+				EnableSQLLogging: true,
+			},
+			assert: func(a *assert.Assertions, entries []map[string]any) {
+				a.Len(entries, 1)
+				a.Equal("info", entries[0]["level"])
+				a.Equal("SELECT `name` FROM `labelled` WHERE name = \"secret\"", entries[0]["sql"])
+				a.Equal("labelled", entries[0]["table"])
+				a.Equal("request-1", entries[0]["request_id"])
+				a.Equal(float64(0), entries[0]["rows"])
+			},
+		},
+		"redacted": {
+			config: &gorm.Config{
+				EnableSQLLogging:    true,
+				RedactSQLParameters: true,
+			},
+			assert: func(a *assert.Assertions, entries []map[string]any) {
+				a.Len(entries, 1)
+				a.Equal("SELECT `name` FROM `labelled` WHERE name = ?", entries[0]["sql"])
+			},
+		},
+		"slow": {
+			config: &gorm.Config{
+				SlowQueryThreshold: time.Nanosecond,
+			},
+			assert: func(a *assert.Assertions, entries []map[string]any) {
+				a.Len(entries, 1)
+				a.Equal("warn", entries[0]["level"])
+				a.Equal(true, entries[0]["slow"])
+			},
+		},
+		"not slow": {
+			config: &gorm.Config{
+				SlowQueryThreshold: -1,
+			},
+			assert: func(a *assert.Assertions, entries []map[string]any) {
+				a.Empty(entries)
+			},
+		},
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+
+			a := assert.New(t)
+			r := require.New(t)
+
+			v.config.Dialect = gorm.DialectSqlite
+			v.config.Name = filepath.Join(t.TempDir(), "logger")
+
+			db, err := gorm.NewDB(v.config)
+			r.NoError(err)
+
+			sqlDB, err := db.DB()
+			r.NoError(err)
+			defer func() {
+				r.NoError(sqlDB.Close())
+			}()
+
+			r.NoError(db.Session(&gorm2.Session{Logger: gormlogger.Discard}).
+				Exec("CREATE TABLE labelled (name VARCHAR(64) PRIMARY KEY)").Error)
+
+			buf := &bytes.Buffer{}
+			ctx := zerolog.New(buf).WithContext(data.WithRequestID(context.Background(), "request-1"))
+
+			var names []string
+			r.NoError(db.WithContext(ctx).Table("labelled").Where("name = ?", "secret").Pluck("name", &names).Error)
+
+			var entries []map[string]any
+			dec := json.NewDecoder(buf)
+			for dec.More() {
+				var e map[string]any
+				r.NoError(dec.Decode(&e))
+				entries = append(entries, e)
+			}
+
+			v.assert(a, entries)
+		})
+	}
+}
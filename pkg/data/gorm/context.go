@@ -10,6 +10,7 @@ import (
 // ContextScope defines a mechanism to apply scoped queries and inject contextual values into entities.
 type ContextScope struct {
 	// QueryModifier customizes database queries using context-specific rules based on the provided gorm.DB instance.
+	// Its conditions also restrict the rows updated by upserts, so columns should be qualified with the table.
 	QueryModifier func(*gorm.DB) *gorm.DB
 	// ValueInjector specifies how to modify or enhance an entity with context-derived information.
 	ValueInjector func(e any)
@@ -3,18 +3,35 @@ package gorm
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"time"
 
 	"github.com/activatedio/datainfra/pkg/data"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
+// DefaultBatchSize is the default number of entities per statement in batch operations.
+const DefaultBatchSize = 100
+
 // FindBuilder defines a function type for building queries to find entities in the database based on a given key.
 type FindBuilder[K comparable] func(ctx context.Context, tx *gorm.DB, key K) *gorm.DB
 
+// FindKeysBuilder defines a function type for building queries to find entities in the database with any of the
+// given keys.
+type FindKeysBuilder[K comparable] func(ctx context.Context, tx *gorm.DB, keys []K) *gorm.DB
+
 type crudTemplateImpl[E any, I any, K comparable] struct {
-	template    MappingTemplate[E, I]
-	findBuilder FindBuilder[K]
+	template        MappingTemplate[E, I]
+	findBuilder     FindBuilder[K]
+	findKeysBuilder FindKeysBuilder[K]
+	versionColumn   string
+	conflictColumns []string
+	batchSize       int
+	historyTable    string
+	outboxTable     string
+	entityType      string
 }
 
 // MappingCrudTemplateImplOptions provides configuration options for creating a mapping-based CRUD template implementation.
@@ -24,13 +41,80 @@ type MappingCrudTemplateImplOptions[E any, I any, K comparable] struct {
 	Template MappingTemplate[E, I]
 	// FindBuilder is an optional query builder function for locating entities, defaulting to queries based on "id".
 	FindBuilder FindBuilder[K]
+	// FindKeysBuilder is an optional query builder function for locating entities by many keys in batch operations.
+	// Without it, batch operations on keys locate entities one at a time.
+	FindKeysBuilder FindKeysBuilder[K]
+	// VersionColumn is an optional integer column used for optimistic locking. When set, updates require the version
+	// of the entity to match the stored version, and increment it.
+	VersionColumn string
+	// BatchSize is the number of entities per statement in batch operations. Defaults to DefaultBatchSize.
+	BatchSize int
+	// ConflictColumns are the columns of the unique constraint which is the conflict target of upserts. Defaults to
+	// the primary key of the model.
+	ConflictColumns []string
+	// HistoryTable is an optional table recording each create, update and delete of an entity, within the same
+	// transaction as the change. See NewMappingHistoryTemplate for its columns.
+	HistoryTable string
+	// OutboxTable is an optional table to which an event is appended for each create, update and delete of an entity,
+	// within the same transaction as the change, to be published by an OutboxRelay. The table has the columns id, an
+	// auto incremented primary key, entity_type, entity_key, the JSON encoded key, operation, the nullable JSON
+	// payload, and created_at.
+	OutboxTable string
+	// EntityType is the entity type of the events appended to the outbox. Defaults to the table of the template.
+	EntityType string
+}
+
+// CrudTemplates is a CRUD template which is also the batch, upsert and patch template of its configuration.
+type CrudTemplates[E any, K comparable] interface {
+	data.CrudTemplate[E, K]
+	data.BatchTemplate[E, K]
+	data.UpsertTemplate[E]
+	data.PatchTemplate[E, K]
+}
+
+// NewMappingCrudTemplates creates a single template serving as the CRUD, batch, upsert and patch template, so that
+// they share one configuration.
+func NewMappingCrudTemplates[E any, I any, K comparable](options MappingCrudTemplateImplOptions[E, I, K]) CrudTemplates[E, K] {
+	return newCrudTemplateImpl(options)
 }
 
 // NewMappingCrudTemplate creates a generic CRUD template using a mapping template and optional find builder configuration.
 func NewMappingCrudTemplate[E any, I any, K comparable](options MappingCrudTemplateImplOptions[E, I, K]) data.CrudTemplate[E, K] {
+	return newCrudTemplateImpl(options)
+}
+
+// NewMappingBatchTemplate creates a batch template using the same configuration as a CRUD template.
+func NewMappingBatchTemplate[E any, I any, K comparable](options MappingCrudTemplateImplOptions[E, I, K]) data.BatchTemplate[E, K] {
+	return newCrudTemplateImpl(options)
+}
+
+// NewMappingUpsertTemplate creates an upsert template using the same configuration as a CRUD template.
+func NewMappingUpsertTemplate[E any, I any, K comparable](options MappingCrudTemplateImplOptions[E, I, K]) data.UpsertTemplate[E] {
+	return newCrudTemplateImpl(options)
+}
+
+// NewMappingPatchTemplate creates a patch template using the same configuration as a CRUD template.
+func NewMappingPatchTemplate[E any, I any, K comparable](options MappingCrudTemplateImplOptions[E, I, K]) data.PatchTemplate[E, K] {
+	return newCrudTemplateImpl(options)
+}
+
+func newCrudTemplateImpl[E any, I any, K comparable](options MappingCrudTemplateImplOptions[E, I, K]) *crudTemplateImpl[E, I, K] {
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
 	return &crudTemplateImpl[E, I, K]{
-		template:    options.Template,
-		findBuilder: options.FindBuilder,
+		template:        options.Template,
+		findBuilder:     options.FindBuilder,
+		findKeysBuilder: options.FindKeysBuilder,
+		versionColumn:   options.VersionColumn,
+		conflictColumns: options.ConflictColumns,
+		batchSize:       batchSize,
+		historyTable:    options.HistoryTable,
+		outboxTable:     options.OutboxTable,
+		entityType:      options.EntityType,
 	}
 }
 
@@ -41,17 +125,39 @@ type CrudTemplateImplOptions[E any, K comparable] struct {
 	Template Template[E]
 	// Optional column to use for the find method. Defaults to "id"
 	FindBuilder FindBuilder[K]
+	// Optional query builder for locating entities by many keys in batch operations
+	FindKeysBuilder FindKeysBuilder[K]
+	// VersionColumn is an optional integer column used for optimistic locking.
+	VersionColumn string
+	// BatchSize is the number of entities per statement in batch operations. Defaults to DefaultBatchSize.
+	BatchSize int
+	// ConflictColumns are the columns of the unique constraint which is the conflict target of upserts.
+	ConflictColumns []string
+	// HistoryTable is an optional table recording each change of an entity.
+	HistoryTable string
+	// OutboxTable is an optional table to which an event is appended for each change of an entity.
+	OutboxTable string
+	// EntityType is the entity type of the events appended to the outbox.
+	EntityType string
 }
 
 // NewCrudTemplate creates a CRUD template for managing entities of type E with a key of type K using specified options.
 func NewCrudTemplate[E any, K comparable](options CrudTemplateImplOptions[E, K]) data.CrudTemplate[E, K] {
 	return NewMappingCrudTemplate[E, E, K](MappingCrudTemplateImplOptions[E, E, K]{
-		Template:    options.Template,
-		FindBuilder: options.FindBuilder,
+		Template:        options.Template,
+		FindBuilder:     options.FindBuilder,
+		FindKeysBuilder: options.FindKeysBuilder,
+		VersionColumn:   options.VersionColumn,
+		BatchSize:       options.BatchSize,
+		ConflictColumns: options.ConflictColumns,
+		HistoryTable:    options.HistoryTable,
+		OutboxTable:     options.OutboxTable,
+		EntityType:      options.EntityType,
 	})
 }
 
-// Find retrieves a single entity of type E based on the criteria defined by the criteriaBuilder function.
+// Find retrieves a single entity of type E based on the criteria defined by the criteriaBuilder function. Returns
+// data.EntityNotFound if no entity matches the criteria within the context scope.
 func (c *crudTemplateImpl[E, I, K]) Find(ctx context.Context, criteriaBuilder func(tx *gorm.DB) *gorm.DB) (E, error) {
 	return c.template.DoFind(ctx, func(db *gorm.DB, entry I) (*gorm.DB, error) {
 		if criteriaBuilder != nil {
@@ -61,22 +167,17 @@ func (c *crudTemplateImpl[E, I, K]) Find(ctx context.Context, criteriaBuilder fu
 	})
 }
 
-// FindByKey retrieves a single entity of type E using the provided key K and the defined findBuilder logic.
+// FindByKey retrieves a single entity of type E using the provided key K and the defined findBuilder logic. Returns
+// data.EntityNotFound if no entity with the key exists within the context scope.
 func (c *crudTemplateImpl[E, I, K]) FindByKey(ctx context.Context, key K) (E, error) {
 	return c.template.DoFind(ctx, func(db *gorm.DB, entry I) (*gorm.DB, error) {
 		return c.findBuilder(ctx, db, key).Find(entry), nil
 	})
 }
 
-// ExistsByKey checks if an entity with the specified key exists in the database and returns a boolean result with an error.
+// ExistsByKey checks if an entity with the specified key exists within the context scope, without loading the entity.
 func (c *crudTemplateImpl[E, I, K]) ExistsByKey(ctx context.Context, key K) (bool, error) {
-
-	var got any
-	var err error
-
-	got, err = c.FindByKey(ctx, key)
-
-	return got != nil, err
+	return c.keyExists(ctx, key, data.FetchTypeKeys)
 }
 
 // List retrieves a paginated list of entities of type E based on the provided filter and pagination parameters.
@@ -89,18 +190,41 @@ func (c *crudTemplateImpl[E, I, K]) ListAll(ctx context.Context, params data.Lis
 	return c.template.DoList(ctx, nil, params)
 }
 
+// Count returns the number of entities ListAll would return across all pages for the list parameters.
+func (c *crudTemplateImpl[E, I, K]) Count(ctx context.Context, params data.ListParams) (int64, error) {
+	return c.template.DoCount(ctx, nil, params)
+}
+
 // Create inserts a new entity into the database, ignoring conflicts if the entity already exists and returns an error if any occur.
+// The audit columns, if any, record the creation.
 func (c *crudTemplateImpl[E, I, K]) Create(ctx context.Context, entity E) error {
-	// TODO - Add validation call
+	return c.instrumented(ctx, OperationCreate, func(ctx context.Context) error {
+		return c.withChanges(ctx, c.entityKey(entity), func(ctx context.Context) (data.HistoryOperation, error) {
+			return data.HistoryOperationCreate, c.create(ctx, entity)
+		})
+	})
+}
+
+// create inserts the entity, as described for Create.
+func (c *crudTemplateImpl[E, I, K]) create(ctx context.Context, entity E) error {
+
+	if err := validateLabels(entity); err != nil {
+		return err
+	}
 
 	internal := c.template.ToInternal(entity)
 	c.template.ApplyContextScopeValueInjector(ctx, internal, data.FetchTypeNone)
 
-	tx := GetDB(ctx).Table(c.template.GetTable()).Clauses(clause.OnConflict{DoNothing: true}).Create(internal)
+	if err := c.template.ApplyAuditValues(ctx, internal, true); err != nil {
+		return err
+	}
+
+	db := GetDB(ctx)
+	tx := db.Table(c.template.GetTable()).Clauses(onConflict(db, clause.OnConflict{DoNothing: true})).Create(internal)
 
 	switch {
 	case tx.Error != nil:
-		return tx.Error
+		return TranslateError(tx.Error)
 	case tx.RowsAffected == 0:
 		return data.EntityAlreadyExists{}
 	default:
@@ -108,26 +232,531 @@ func (c *crudTemplateImpl[E, I, K]) Create(ctx context.Context, entity E) error
 	}
 }
 
-// Update modifies an existing entity in the database and returns an error if the operation fails.
+// Update modifies an existing entity in the database, within the context scope. Returns data.EntityNotFound if no
+// entity with the key exists in the scope. With a version column, the version of the entity must match the stored
+// version, otherwise data.StaleVersion is returned, and the version of the entity is incremented. The created audit
+// columns, if any, are left unchanged.
 func (c *crudTemplateImpl[E, I, K]) Update(ctx context.Context, entity E) error {
+	return c.instrumented(ctx, OperationUpdate, func(ctx context.Context) error {
+		return c.withChanges(ctx, c.entityKey(entity), func(ctx context.Context) (data.HistoryOperation, error) {
+			return data.HistoryOperationUpdate, c.update(ctx, entity)
+		})
+	})
+}
+
+// update modifies the entity, as described for Update.
+func (c *crudTemplateImpl[E, I, K]) update(ctx context.Context, entity E) error {
+
+	if err := validateLabels(entity); err != nil {
+		return err
+	}
+
+	internal := c.template.ToInternal(entity)
+	c.template.ApplyContextScopeValueInjector(ctx, internal, data.FetchTypeNone)
+
+	if err := c.template.ApplyAuditValues(ctx, internal, false); err != nil {
+		return err
+	}
+
+	tx := GetDB(ctx).Table(c.template.GetTable()).Model(internal)
+	tx = c.template.ApplyContextScopeQueryBuilder(ctx, tx, data.FetchTypeNone)
+
+	var restoreVersion func()
+
+	if c.versionColumn != "" {
+
+		sch, err := parseSchema(tx, internal)
+		if err != nil {
+			return err
+		}
+
+		f := sch.LookUpField(c.versionColumn)
+		if f == nil {
+			return fmt.Errorf("version column %s not found in %s", c.versionColumn, sch.Name)
+		}
+
+		rv := reflect.ValueOf(internal)
+		current, _ := f.ValueOf(ctx, rv)
+
+		next, err := nextVersion(current)
+		if err != nil {
+			return err
+		}
+
+		tx = tx.Where(fmt.Sprintf("%s = ?", qualifyColumn(c.template.GetTable(), c.versionColumn)), current)
+
+		if err = f.Set(ctx, rv, next); err != nil {
+			return err
+		}
+
+		restoreVersion = func() {
+			_ = f.Set(ctx, rv, current)
+		}
+	}
+
+	// Select all fields so that zero values are updated, the where clause is built from the primary key
+	tx = tx.Select("*")
+
+	if audit := c.template.GetAuditColumns(); audit != nil && len(audit.CreatedColumns()) > 0 {
+		tx = tx.Omit(audit.CreatedColumns()...)
+	}
+
+	tx = tx.Updates(internal)
+
+	if restoreVersion != nil && (tx.Error != nil || tx.RowsAffected == 0) {
+		restoreVersion()
+	}
+
+	switch {
+	case tx.Error != nil:
+		return TranslateError(tx.Error)
+	case tx.RowsAffected == 0:
+		if c.versionColumn != "" {
+			exists, err := c.existsInScope(ctx, internal)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return data.StaleVersion{}
+			}
+		}
+		return data.EntityNotFound{}
+	default:
+		return nil
+	}
+}
+
+// Upsert inserts the entity, or updates the columns of the existing row with the same values of the conflict columns,
+// with an INSERT ... ON CONFLICT DO UPDATE statement conditioned on the context scope. Context scope values are injected
+// before the statement. If the existing row is outside the context scope, data.ScopeViolation is returned and the row
+// is not updated. A soft deleted row is restored by the update. With a version column, the stored version is
+// incremented on update. Returns true if the entity was inserted.
+func (c *crudTemplateImpl[E, I, K]) Upsert(ctx context.Context, entity E) (bool, error) {
+	var inserted bool
+
+	err := c.withChanges(ctx, c.entityKey(entity), func(ctx context.Context) (data.HistoryOperation, error) {
+		var err error
+		if inserted, err = c.upsert(ctx, entity); inserted {
+			return data.HistoryOperationCreate, err
+		}
+		return data.HistoryOperationUpdate, err
+	})
+
+	return inserted, err
+}
+
+// upsert inserts or updates the entity, as described for Upsert.
+func (c *crudTemplateImpl[E, I, K]) upsert(ctx context.Context, entity E) (bool, error) {
+
+	if err := validateLabels(entity); err != nil {
+		return false, err
+	}
 
 	internal := c.template.ToInternal(entity)
 	c.template.ApplyContextScopeValueInjector(ctx, internal, data.FetchTypeNone)
 
-	return GetDB(ctx).Table(c.template.GetTable()).Save(internal).Error
+	if err := c.template.ApplyAuditValues(ctx, internal, true); err != nil {
+		return false, err
+	}
+
+	table := c.template.GetTable()
+	db := GetDB(ctx)
+
+	sch, err := parseSchema(db, internal)
+	if err != nil {
+		return false, err
+	}
+
+	fields, err := c.conflictFields(sch)
+	if err != nil {
+		return false, err
+	}
+
+	conflict := c.upsertClause(sch, fields)
+
+	// The existing row is only updated within the context scope, including soft deleted rows which are restored
+	scoped := c.template.ApplyContextScopeQueryBuilder(data.WithDeleted(ctx), db.Session(&gorm.Session{NewDB: true}).Table(table), data.FetchTypeNone)
+	if conds := db.Statement.BuildCondition(scoped); len(conds) > 0 {
+		conflict.Where = clause.Where{Exprs: conds}
+	}
+
+	rows, inserted, err := upsert(db.Table(table), internal, conflict)
+	if err != nil {
+		return false, TranslateError(err)
+	}
+
+	if rows == 0 {
+		return false, data.ScopeViolation{Reason: fmt.Sprintf("entity in %s exists outside of the current scope", table)}
+	}
+
+	return inserted, nil
+}
+
+// conflictFields returns the fields of the conflict columns, which default to the primary key.
+func (c *crudTemplateImpl[E, I, K]) conflictFields(sch *schema.Schema) ([]*schema.Field, error) {
+
+	if len(c.conflictColumns) == 0 {
+		if len(sch.PrimaryFields) == 0 {
+			return nil, fmt.Errorf("no primary key in %s", sch.Name)
+		}
+		return sch.PrimaryFields, nil
+	}
+
+	fields := make([]*schema.Field, len(c.conflictColumns))
+
+	for i, col := range c.conflictColumns {
+		if fields[i] = sch.LookUpField(col); fields[i] == nil {
+			return nil, fmt.Errorf("conflict column %s not found in %s", col, sch.Name)
+		}
+	}
+
+	return fields, nil
+}
+
+// upsertClause returns the clause updating all columns other than the conflict columns and the primary key with the
+// inserted values, and incrementing the version.
+func (c *crudTemplateImpl[E, I, K]) upsertClause(sch *schema.Schema, fields []*schema.Field) clause.OnConflict {
+
+	conflict := clause.OnConflict{}
+	skip := map[string]bool{}
+
+	for _, f := range fields {
+		conflict.Columns = append(conflict.Columns, clause.Column{Name: f.DBName})
+		skip[f.DBName] = true
+	}
+
+	for _, f := range sch.PrimaryFields {
+		skip[f.DBName] = true
+	}
+
+	if audit := c.template.GetAuditColumns(); audit != nil {
+		for _, col := range audit.CreatedColumns() {
+			skip[col] = true
+		}
+	}
+
+	var updates []string
+
+	for _, f := range sch.Fields {
+		if f.DBName == "" || skip[f.DBName] || f.DBName == c.versionColumn || !f.Updatable {
+			continue
+		}
+		updates = append(updates, f.DBName)
+	}
+
+	conflict.DoUpdates = clause.AssignmentColumns(updates)
+
+	if c.versionColumn != "" {
+		conflict.DoUpdates = append(conflict.DoUpdates, clause.Assignment{
+			Column: clause.Column{Name: c.versionColumn},
+			Value:  gorm.Expr(fmt.Sprintf("%s + 1", qualifyColumn(c.template.GetTable(), c.versionColumn))),
+		})
+	}
+
+	return conflict
+}
+
+// Patch updates the columns of the fields in the field mask with the values of the entity, for the entity with the
+// key within the context scope. Fields are named by their column or their field name. Primary key, soft delete, audit
+// and read only fields may not be patched, and the updated audit columns are set. With a version column, the stored
+// version is incremented, and the new version is set on the entity. If the mask includes the version, the version of
+// the entity must match the stored version, otherwise data.StaleVersion is returned.
+func (c *crudTemplateImpl[E, I, K]) Patch(ctx context.Context, key K, fieldMask []string, entity E) error {
+	return c.withChanges(ctx, fixedKey(key), func(ctx context.Context) (data.HistoryOperation, error) {
+		return data.HistoryOperationUpdate, c.patch(ctx, key, fieldMask, entity)
+	})
+}
+
+// patch updates the fields of the mask, as described for Patch.
+func (c *crudTemplateImpl[E, I, K]) patch(ctx context.Context, key K, fieldMask []string, entity E) error {
+
+	if len(fieldMask) == 0 {
+		return data.InvalidFieldMask{Reason: "no fields"}
+	}
+
+	if err := validateLabels(entity); err != nil {
+		return err
+	}
+
+	table := c.template.GetTable()
+	internal := c.template.ToInternal(entity)
+
+	sch, err := parseSchema(GetDB(ctx).Table(table), internal)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(internal)
+	updates := map[string]any{}
+	audit := c.template.GetAuditColumns()
+
+	var expectedVersion any
+
+	for _, name := range fieldMask {
+
+		f := sch.LookUpField(name)
+
+		switch {
+		case f == nil || f.DBName == "":
+			return data.InvalidFieldMask{Field: name, Reason: "not a mapped field"}
+		case f.PrimaryKey:
+			return data.InvalidFieldMask{Field: name, Reason: "key fields may not be patched"}
+		case f.DBName == c.template.GetSoftDeleteColumn():
+			return data.InvalidFieldMask{Field: name, Reason: "soft delete fields may not be patched"}
+		case audit != nil && audit.IsAuditColumn(f.DBName):
+			return data.InvalidFieldMask{Field: name, Reason: "audit fields may not be patched"}
+		case !f.Updatable:
+			return data.InvalidFieldMask{Field: name, Reason: "field is read only"}
+		}
+
+		v, _ := f.ValueOf(ctx, rv)
+
+		if f.DBName == c.versionColumn {
+			expectedVersion = v
+			continue
+		}
+
+		updates[f.DBName] = v
+	}
+
+	if audit != nil {
+		if err = c.template.ApplyAuditValues(ctx, internal, false); err != nil {
+			return err
+		}
+		for _, col := range audit.UpdatedColumns() {
+			updates[col], _ = sch.LookUpField(col).ValueOf(ctx, rv)
+		}
+	}
+
+	versionColumn := qualifyColumn(table, c.versionColumn)
+
+	var next any
+
+	switch {
+	case expectedVersion != nil:
+		if next, err = nextVersion(expectedVersion); err != nil {
+			return err
+		}
+		updates[c.versionColumn] = next
+	case c.versionColumn != "":
+		updates[c.versionColumn] = gorm.Expr(fmt.Sprintf("%s + 1", versionColumn))
+	}
+
+	if c.versionColumn == "" {
+		return c.patchColumns(ctx, key, updates, nil)
+	}
+
+	f := sch.LookUpField(c.versionColumn)
+	if f == nil {
+		return fmt.Errorf("version column %s not found in %s", c.versionColumn, sch.Name)
+	}
+
+	if next != nil {
+		if err = c.patchColumns(ctx, key, updates, expectedVersion); err != nil {
+			return err
+		}
+		return f.Set(ctx, rv, next)
+	}
+
+	// The incremented version is read within the transaction of the update, which holds the row until committed
+	return InTx(ctx, func(ctx context.Context) error {
+
+		if err := c.patchColumns(ctx, key, updates, nil); err != nil {
+			return err
+		}
+
+		var stored int64
+
+		read := GetDB(ctx).Table(table)
+		read = c.template.ApplyContextScopeQueryBuilder(ctx, read, data.FetchTypeNone)
+
+		if err := c.findBuilder(ctx, read, key).Select(versionColumn).Limit(1).Scan(&stored).Error; err != nil {
+			return TranslateError(err)
+		}
+
+		return f.Set(ctx, rv, stored)
+	})
+}
+
+// patchColumns updates the columns of the entity with the key within the context scope, and with the expected version
+// if any, as described for Patch.
+func (c *crudTemplateImpl[E, I, K]) patchColumns(ctx context.Context, key K, updates map[string]any,
+	expectedVersion any) error {
+
+	table := c.template.GetTable()
+	tx := GetDB(ctx).Table(table)
+
+	if expectedVersion != nil {
+		tx = tx.Where(fmt.Sprintf("%s = ?", qualifyColumn(table, c.versionColumn)), expectedVersion)
+	}
+
+	tx = c.template.ApplyContextScopeQueryBuilder(ctx, tx, data.FetchTypeNone)
+	tx = c.findBuilder(ctx, tx, key).UpdateColumns(updates)
+
+	switch {
+	case tx.Error != nil:
+		return TranslateError(tx.Error)
+	case tx.RowsAffected == 0:
+		if expectedVersion != nil {
+			exists, err := c.keyExistsInScope(ctx, key)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return data.StaleVersion{}
+			}
+		}
+		return data.EntityNotFound{}
+	default:
+		return nil
+	}
+}
+
+// keyExistsInScope checks if a row with the key exists within the context scope.
+func (c *crudTemplateImpl[E, I, K]) keyExistsInScope(ctx context.Context, key K) (bool, error) {
+	return c.keyExists(ctx, key, data.FetchTypeNone)
+}
+
+// keyExists checks if a row with the key exists within the context scope of the fetch type, selecting at most a
+// single constant rather than the row.
+func (c *crudTemplateImpl[E, I, K]) keyExists(ctx context.Context, key K, fetchType data.FetchType) (bool, error) {
+
+	tx := GetDB(ctx).Table(c.template.GetTable())
+	tx = c.template.ApplyContextScopeQueryBuilder(ctx, tx, fetchType)
+
+	var found []int
+
+	if err := c.findBuilder(ctx, tx, key).Select("1").Limit(1).Find(&found).Error; err != nil {
+		return false, TranslateError(err)
+	}
+
+	return len(found) > 0, nil
 }
 
-// Delete removes an entity of type E from the database based on the provided key K, using the findBuilder logic.
+// existsInScope checks if a row with the primary key of the internal entity exists within the context scope.
+func (c *crudTemplateImpl[E, I, K]) existsInScope(ctx context.Context, internal I) (bool, error) {
+
+	tx := GetDB(ctx).Table(c.template.GetTable())
+	tx = c.template.ApplyContextScopeQueryBuilder(ctx, tx, data.FetchTypeNone)
+
+	sch, err := parseSchema(tx, internal)
+	if err != nil {
+		return false, err
+	}
+
+	rv := reflect.ValueOf(internal)
+
+	for _, f := range sch.PrimaryFields {
+		v, _ := f.ValueOf(ctx, rv)
+		tx = tx.Where(fmt.Sprintf("%s = ?", qualifyColumn(c.template.GetTable(), f.DBName)), v)
+	}
+
+	var count int64
+
+	if err = tx.Count(&count).Error; err != nil {
+		return false, TranslateError(err)
+	}
+
+	return count > 0, nil
+}
+
+// nextVersion returns the version following the current version, which must be an integer.
+func nextVersion(current any) (any, error) {
+
+	v := reflect.ValueOf(current)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() + 1, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() + 1, nil
+	default:
+		return nil, fmt.Errorf("version must be an integer, was %T", current)
+	}
+}
+
+// Delete removes an entity of type E from the database based on the provided key K, using the findBuilder logic,
+// within the context scope. With a soft delete column, the entity is marked as deleted instead. Returns
+// data.EntityNotFound if no entity with the key exists within the context scope.
 func (c *crudTemplateImpl[E, I, K]) Delete(ctx context.Context, key K) error {
+	return c.instrumented(ctx, OperationDelete, func(ctx context.Context) error {
+		return c.withChanges(ctx, fixedKey(key), func(ctx context.Context) (data.HistoryOperation, error) {
+			return data.HistoryOperationDelete, c.delete(ctx, key)
+		})
+	})
+}
+
+// delete removes the entity with the key, as described for Delete.
+func (c *crudTemplateImpl[E, I, K]) delete(ctx context.Context, key K) error {
 	db := GetDB(ctx).Table(c.template.GetTable())
-	return c.findBuilder(ctx, db, key).Delete(new(E)).Error
+	db = c.template.ApplyContextScopeQueryBuilder(ctx, db, data.FetchTypeNone)
+	db = c.findBuilder(ctx, db, key)
+	if col := c.template.GetSoftDeleteColumn(); col != "" {
+		return deleted(db.UpdateColumn(col, time.Now()))
+	}
+	return deleted(db.Delete(new(E)))
 }
 
-// DeleteEntity removes the provided entity of type E from the database and returns an error if the operation fails.
+// DeleteEntity removes the provided entity of type E from the database, within the context scope, and returns an
+// error if the operation fails. With a soft delete column, the entity is marked as deleted instead. Returns
+// data.EntityNotFound if the entity does not exist within the context scope.
 func (c *crudTemplateImpl[E, I, K]) DeleteEntity(ctx context.Context, entity E) error {
-	// TODO - need to validate access
-	// TODO - unit test
-	return GetDB(ctx).Table(c.template.GetTable()).Delete(entity).Error
+	return c.instrumented(ctx, OperationDelete, func(ctx context.Context) error {
+		return c.withChanges(ctx, c.entityKey(entity), func(ctx context.Context) (data.HistoryOperation, error) {
+			return data.HistoryOperationDelete, c.deleteEntity(ctx, entity)
+		})
+	})
+}
+
+// deleteEntity removes the entity, as described for DeleteEntity.
+func (c *crudTemplateImpl[E, I, K]) deleteEntity(ctx context.Context, entity E) error {
+	internal := c.template.ToInternal(entity)
+	db := GetDB(ctx).Table(c.template.GetTable())
+	db = c.template.ApplyContextScopeQueryBuilder(ctx, db, data.FetchTypeNone)
+	if col := c.template.GetSoftDeleteColumn(); col != "" {
+		return deleted(db.Model(internal).UpdateColumn(col, time.Now()))
+	}
+	return deleted(db.Delete(internal))
+}
+
+// deleted returns the error of a delete, which is data.EntityNotFound if no row was deleted.
+func deleted(tx *gorm.DB) error {
+	switch {
+	case tx.Error != nil:
+		return TranslateError(tx.Error)
+	case tx.RowsAffected == 0:
+		return data.EntityNotFound{}
+	default:
+		return nil
+	}
+}
+
+// instrumented runs the write of a single entity with the instrumentation of the template.
+func (c *crudTemplateImpl[E, I, K]) instrumented(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+
+	ctx, end := instrument(ctx, c.template.GetInstrumentation(), &Operation{
+		Name:      name,
+		Table:     c.template.GetTable(),
+		FetchType: data.FetchTypeNone,
+	})
+
+	err := fn(ctx)
+
+	if err != nil {
+		end(0, err)
+	} else {
+		end(1, nil)
+	}
+
+	return err
+}
+
+// validateLabels validates the labels of the entity, if it has any.
+func validateLabels(entity any) error {
+	if wl, ok := entity.(data.WithLabels); ok {
+		return data.ValidateLabels(wl.GetLabels())
+	}
+	return nil
 }
 
 // SingleFindBuilder returns a FindBuilder function that constructs a query to find an entity based on the specified column.
@@ -136,3 +765,11 @@ func SingleFindBuilder[K comparable](findColumn string) FindBuilder[K] {
 		return tx.Where(fmt.Sprintf("%s = ?", findColumn), key)
 	}
 }
+
+// SingleFindKeysBuilder returns a FindKeysBuilder function that constructs a query to find entities with any of the
+// keys in the specified column.
+func SingleFindKeysBuilder[K comparable](findColumn string) FindKeysBuilder[K] {
+	return func(_ context.Context, tx *gorm.DB, keys []K) *gorm.DB {
+		return tx.Where(fmt.Sprintf("%s IN ?", findColumn), keys)
+	}
+}
@@ -0,0 +1,381 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	reflect2 "github.com/activatedio/datainfra/pkg/reflect"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// FindByKeys retrieves the entities with the keys, in the order of the keys, within the context scope. Entities are
+// queried in chunks of the batch size with the FindKeysBuilder, or one at a time without it.
+func (c *crudTemplateImpl[E, I, K]) FindByKeys(ctx context.Context, keys []K) ([]E, error) {
+
+	results := make([]E, len(keys))
+
+	if c.findKeysBuilder == nil {
+		for i, k := range keys {
+			e, err := c.FindByKey(ctx, k)
+			if err != nil && !errors.Is(err, data.EntityNotFound{}) {
+				return nil, err
+			}
+			results[i] = e
+		}
+		return results, nil
+	}
+
+	found := map[K]E{}
+
+	for _, chunk := range chunks(keys, c.batchSize) {
+
+		tx := GetDB(ctx).Table(c.template.GetTable())
+		tx = c.template.ApplyContextScopeQueryBuilder(ctx, tx, data.FetchTypeList)
+
+		var rows []I
+
+		if err := c.findKeysBuilder(ctx, tx, chunk).Find(&rows).Error; err != nil {
+			return nil, TranslateError(err)
+		}
+
+		for _, row := range rows {
+			k, err := c.keyOf(ctx, tx, row)
+			if err != nil {
+				return nil, err
+			}
+			found[k] = c.template.FromInternal(row)
+		}
+	}
+
+	for i, k := range keys {
+		results[i] = found[k]
+	}
+
+	return results, nil
+}
+
+// keyOf returns the key of an internal entity. A key of the type of the single primary key field takes its value,
+// while a composite key struct takes the values of the entity fields with the same names as its fields.
+func (c *crudTemplateImpl[E, I, K]) keyOf(ctx context.Context, tx *gorm.DB, row I) (K, error) {
+
+	var k K
+
+	sch, err := parseSchema(tx, row)
+	if err != nil {
+		return k, err
+	}
+
+	if len(sch.PrimaryFields) == 1 {
+		v, _ := sch.PrimaryFields[0].ValueOf(ctx, reflect.ValueOf(row))
+		if pk, ok := v.(K); ok {
+			return pk, nil
+		}
+	}
+
+	kv := reflect.ValueOf(&k).Elem()
+
+	if kv.Kind() != reflect.Struct {
+		return k, fmt.Errorf("primary key of %s does not match key %T", sch.Name, k)
+	}
+
+	for i := 0; i < kv.NumField(); i++ {
+		name := kv.Type().Field(i).Name
+		f := sch.LookUpField(name)
+		if f == nil {
+			return k, fmt.Errorf("no field %s in %s for key %T", name, sch.Name, k)
+		}
+		v, _ := f.ValueOf(ctx, reflect.ValueOf(row))
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() || !rv.Type().AssignableTo(kv.Field(i).Type()) {
+			return k, fmt.Errorf("field %s of %s is %T, expected %s", name, sch.Name, v, kv.Field(i).Type())
+		}
+		kv.Field(i).Set(rv)
+	}
+
+	return k, nil
+}
+
+// CreateMany inserts the entities using multi-row inserts in chunks of the batch size, within a transaction. Entities
+// whose key exists within the context scope, or repeats the key of an earlier entity, are not inserted and their
+// result is data.EntityAlreadyExists. If a chunk conflicts with rows outside the context scope, such as soft deleted
+// rows or rows inserted concurrently, its entities are inserted one at a time, and as with Create, those conflicting
+// are not inserted and their result is data.EntityAlreadyExists. With a history or outbox table, each creation is
+// recorded.
+func (c *crudTemplateImpl[E, I, K]) CreateMany(ctx context.Context, entities []E) ([]error, error) {
+
+	for _, e := range entities {
+		if err := validateLabels(e); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]error, len(entities))
+
+	err := InTx(ctx, func(ctx context.Context) error {
+
+		seen := map[K]bool{}
+
+		for i, chunk := range chunks(entities, c.batchSize) {
+			start := i * c.batchSize
+			if err := c.createChunk(ctx, chunk, results[start:start+len(chunk)], seen); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, TranslateError(err)
+	}
+
+	return results, nil
+}
+
+// createChunk inserts a chunk of entities with a single statement, recording the result of each entity. Seen holds
+// the keys of entities of earlier chunks.
+func (c *crudTemplateImpl[E, I, K]) createChunk(ctx context.Context, entities []E, results []error, seen map[K]bool) error {
+
+	table := c.template.GetTable()
+	db := GetDB(ctx)
+
+	internals := make([]I, len(entities))
+	keys := make([]K, len(entities))
+
+	for i, e := range entities {
+
+		internal := c.template.ToInternal(e)
+		c.template.ApplyContextScopeValueInjector(ctx, internal, data.FetchTypeNone)
+		if err := c.template.ApplyAuditValues(ctx, internal, true); err != nil {
+			return err
+		}
+		internals[i] = internal
+
+		k, err := c.keyOf(ctx, db, internal)
+		if err != nil {
+			return err
+		}
+		keys[i] = k
+	}
+
+	existing, err := c.existingKeys(ctx, db.Table(table), keys)
+	if err != nil {
+		return err
+	}
+
+	var toCreate []I
+	var toCreateResults []*error
+
+	for i, k := range keys {
+		if existing[k] || seen[k] {
+			results[i] = data.EntityAlreadyExists{}
+			continue
+		}
+		seen[k] = true
+		toCreate = append(toCreate, internals[i])
+		toCreateResults = append(toCreateResults, &results[i])
+	}
+
+	if len(toCreate) == 0 {
+		return nil
+	}
+
+	// The chunk is inserted within a savepoint, so that it is inserted one at a time if any entity conflicts
+	err = InTx(ctx, func(ctx context.Context) error {
+
+		tx := GetDB(ctx).Table(table).Clauses(onConflict(db, clause.OnConflict{DoNothing: true})).Create(toCreate)
+
+		switch {
+		case tx.Error != nil:
+			return tx.Error
+		case int(tx.RowsAffected) != len(toCreate):
+			return errChunkConflict
+		default:
+			return nil
+		}
+	})
+
+	if errors.Is(err, errChunkConflict) {
+		toCreate, err = c.createEach(ctx, toCreate, toCreateResults)
+	}
+
+	switch {
+	case err != nil:
+		return err
+	case c.recordsChanges():
+		return c.recordCreated(ctx, db, toCreate)
+	default:
+		return nil
+	}
+}
+
+// errChunkConflict indicates that some entities of a chunk were not inserted, since they conflict with existing rows.
+var errChunkConflict = errors.New("chunk conflicts with existing rows")
+
+// createEach inserts the internal entities one at a time, setting the result of each entity not inserted since it
+// conflicts with an existing row to data.EntityAlreadyExists. Returns the internal entities inserted.
+func (c *crudTemplateImpl[E, I, K]) createEach(ctx context.Context, internals []I, results []*error) ([]I, error) {
+
+	db := GetDB(ctx)
+
+	var created []I
+
+	for i, internal := range internals {
+
+		tx := db.Table(c.template.GetTable()).Clauses(onConflict(db, clause.OnConflict{DoNothing: true})).Create(internal)
+
+		switch {
+		case tx.Error != nil:
+			return nil, tx.Error
+		case tx.RowsAffected == 0:
+			*results[i] = data.EntityAlreadyExists{}
+		default:
+			created = append(created, internal)
+		}
+	}
+
+	return created, nil
+}
+
+// UpdateMany updates each of the entities within a transaction, so that either all or none of them are updated.
+func (c *crudTemplateImpl[E, I, K]) UpdateMany(ctx context.Context, entities []E) error {
+	return InTx(ctx, func(ctx context.Context) error {
+		for i, e := range entities {
+			if err := c.Update(ctx, e); err != nil {
+				return fmt.Errorf("update of entity %d: %w", i, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteByKeys removes the entities with the keys within the context scope and a transaction. Entities are deleted in
+// chunks of the batch size with the FindKeysBuilder, or one at a time without it or with a history or outbox table.
+// With a soft delete column, the entities are marked as deleted instead.
+func (c *crudTemplateImpl[E, I, K]) DeleteByKeys(ctx context.Context, keys []K) error {
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return InTx(ctx, func(ctx context.Context) error {
+
+		// Entities are deleted one at a time with a history or outbox table, so that each deletion is recorded
+		if c.findKeysBuilder == nil || c.recordsChanges() {
+			for _, k := range keys {
+				if err := c.Delete(ctx, k); err != nil && !errors.Is(err, data.EntityNotFound{}) {
+					return err
+				}
+			}
+			return nil
+		}
+
+		for _, chunk := range chunks(keys, c.batchSize) {
+
+			db := GetDB(ctx).Table(c.template.GetTable())
+			db = c.template.ApplyContextScopeQueryBuilder(ctx, db, data.FetchTypeNone)
+			db = c.findKeysBuilder(ctx, db, chunk)
+
+			if col := c.template.GetSoftDeleteColumn(); col != "" {
+				db = db.UpdateColumn(col, time.Now())
+			} else {
+				db = db.Delete(new(E))
+			}
+
+			if db.Error != nil {
+				return TranslateError(db.Error)
+			}
+		}
+
+		return nil
+	})
+}
+
+// chunks splits the values into consecutive chunks of at most size values.
+func chunks[T any](values []T, size int) [][]T {
+
+	var res [][]T
+
+	for start := 0; start < len(values); start += size {
+		res = append(res, values[start:min(start+size, len(values))])
+	}
+
+	return res
+}
+
+// keyColumns returns the qualified columns of the key, in the order of the fields of a composite key struct.
+func (c *crudTemplateImpl[E, I, K]) keyColumns(sch *schema.Schema) ([]string, error) {
+
+	table := c.template.GetTable()
+	kt := reflect.TypeFor[K]()
+
+	if len(sch.PrimaryFields) == 1 && sch.PrimaryFields[0].FieldType == kt {
+		return []string{qualifyColumn(table, sch.PrimaryFields[0].DBName)}, nil
+	}
+
+	if kt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("primary key of %s does not match key %s", sch.Name, kt)
+	}
+
+	columns := make([]string, kt.NumField())
+
+	for i := range columns {
+		name := kt.Field(i).Name
+		f := sch.LookUpField(name)
+		if f == nil {
+			return nil, fmt.Errorf("no field %s in %s for key %s", name, sch.Name, kt)
+		}
+		columns[i] = qualifyColumn(table, f.DBName)
+	}
+
+	return columns, nil
+}
+
+// existingKeys returns the keys which exist in the table within the context scope.
+func (c *crudTemplateImpl[E, I, K]) existingKeys(ctx context.Context, tx *gorm.DB, keys []K) (map[K]bool, error) {
+
+	res := map[K]bool{}
+
+	if len(keys) == 0 {
+		return res, nil
+	}
+
+	sch, err := parseSchema(tx, reflect2.ZeroInterface[I]())
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := c.keyColumns(sch)
+	if err != nil {
+		return nil, err
+	}
+
+	cond, err := keysInCondition(columns, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	tx = c.template.ApplyContextScopeQueryBuilder(ctx, tx, data.FetchTypeNone)
+
+	var rows []I
+
+	if err = tx.Select(columns).Where(cond).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		k, err := c.keyOf(ctx, tx, row)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = true
+	}
+
+	return res, nil
+}
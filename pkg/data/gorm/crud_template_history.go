@@ -0,0 +1,309 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	reflect2 "github.com/activatedio/datainfra/pkg/reflect"
+	"gorm.io/gorm"
+)
+
+// historyRow is a row of a history table.
+type historyRow struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	EntityKey  string    `gorm:"column:entity_key"`
+	Scope      string    `gorm:"column:scope"`
+	Operation  string    `gorm:"column:operation"`
+	Actor      string    `gorm:"column:actor"`
+	ChangedAt  time.Time `gorm:"column:changed_at"`
+	BeforeData *string   `gorm:"column:before_data"`
+	AfterData  *string   `gorm:"column:after_data"`
+}
+
+// NewMappingHistoryTemplate creates a history template reading the history table of a CRUD template with the same
+// configuration. The history table has the columns id, an auto incremented primary key, entity_key, the JSON encoded
+// key, scope, the JSON encoded values injected by the context scope or empty without one, operation, actor, changed_at,
+// and before_data and after_data, the nullable JSON snapshots of the stored entity.
+func NewMappingHistoryTemplate[E any, I any, K comparable](options MappingCrudTemplateImplOptions[E, I, K]) data.HistoryTemplate[K] {
+	return newCrudTemplateImpl(options)
+}
+
+// ListHistory lists the recorded changes of the entity with the key within the context scope in the order they were
+// recorded, which is reversed by sorting on id descending. Changes are matched on the scope recorded with them rather
+// than the stored entity, since the entity may no longer exist.
+func (c *crudTemplateImpl[E, I, K]) ListHistory(ctx context.Context, key K, params data.ListParams) (*data.List[*data.HistoryEntry], error) {
+
+	entityKey, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	db := GetDB(ctx)
+
+	scope, err := c.scopeValues(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := db.Table(c.historyTable).Where(qualifyColumn(c.historyTable, "entity_key")+" = ?", string(entityKey)).
+		Where(qualifyColumn(c.historyTable, "scope")+" = ?", scope)
+
+	sch, err := parseSchema(tx, &historyRow{})
+	if err != nil {
+		return nil, err
+	}
+
+	terms, err := sortOrderTerms(c.historyTable, sch, nil, []string{"id"}, params.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newPage(params.PageParams, terms)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*historyRow
+
+	if err = p.apply(tx).Find(&rows).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+
+	rows, token, err := completePage(ctx, sch, p, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*data.HistoryEntry, len(rows))
+
+	for i, r := range rows {
+		result[i] = &data.HistoryEntry{
+			Operation: data.HistoryOperation(r.Operation),
+			Actor:     r.Actor,
+			ChangedAt: r.ChangedAt,
+			Before:    rawSnapshot(r.BeforeData),
+			After:     rawSnapshot(r.AfterData),
+		}
+	}
+
+	return &data.List[*data.HistoryEntry]{
+		NextPageToken: token,
+		List:          result,
+	}, nil
+}
+
+// recordsChanges returns whether changes are recorded in a history or outbox table.
+func (c *crudTemplateImpl[E, I, K]) recordsChanges() bool {
+	return c.historyTable != "" || c.outboxTable != ""
+}
+
+// withChanges makes the change and, with a history or outbox table, records it within the same transaction. The
+// stored entity with the key is captured before and after the change, and nothing is recorded if it is unchanged. The
+// entity is locked as it is captured before the change, so that concurrent changes record the same sequence of
+// snapshots as they make.
+func (c *crudTemplateImpl[E, I, K]) withChanges(ctx context.Context, keyFn func(ctx context.Context) (K, error),
+	change func(ctx context.Context) (data.HistoryOperation, error)) error {
+
+	if !c.recordsChanges() {
+		_, err := change(ctx)
+		return err
+	}
+
+	return InTx(ctx, func(ctx context.Context) error {
+
+		key, err := keyFn(ctx)
+		if err != nil {
+			return err
+		}
+
+		before, err := c.snapshot(ctx, key, true)
+		if err != nil {
+			return err
+		}
+
+		op, err := change(ctx)
+		if err != nil {
+			return err
+		}
+
+		after, err := c.snapshot(ctx, key, false)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(before, after) {
+			return nil
+		}
+
+		return c.recordChange(ctx, key, op, before, after)
+	})
+}
+
+// fixedKey returns a key function for withChanges returning the key.
+func fixedKey[K comparable](key K) func(ctx context.Context) (K, error) {
+	return func(_ context.Context) (K, error) {
+		return key, nil
+	}
+}
+
+// entityKey returns a key function for withChanges returning the key of the entity.
+func (c *crudTemplateImpl[E, I, K]) entityKey(entity E) func(ctx context.Context) (K, error) {
+	return func(ctx context.Context) (K, error) {
+		return c.keyOf(ctx, GetDB(ctx), c.template.ToInternal(entity))
+	}
+}
+
+// snapshot returns the JSON encoding of the stored entity with the key within the context scope, or nil if there is
+// none. With lock, the entity is locked until the transaction ends.
+func (c *crudTemplateImpl[E, I, K]) snapshot(ctx context.Context, key K, lock bool) ([]byte, error) {
+
+	tx := GetDB(ctx).Table(c.template.GetTable())
+	tx = c.template.ApplyContextScopeQueryBuilder(ctx, tx, data.FetchTypeNone)
+
+	if lock {
+		tx = lockForUpdate(tx)
+	}
+
+	var rows []I
+
+	if err := c.findBuilder(ctx, tx, key).Limit(1).Find(&rows).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(rows[0])
+}
+
+// recordChange records the change of the entity with the key in the history and outbox tables, if any.
+func (c *crudTemplateImpl[E, I, K]) recordChange(ctx context.Context, key K, op data.HistoryOperation, before []byte, after []byte) error {
+
+	if c.historyTable != "" {
+		if err := c.recordHistory(ctx, key, op, before, after); err != nil {
+			return err
+		}
+	}
+
+	if c.outboxTable != "" {
+		return c.appendEvent(ctx, key, op, before, after)
+	}
+
+	return nil
+}
+
+// recordHistory inserts a row for the change of the entity with the key into the history table.
+func (c *crudTemplateImpl[E, I, K]) recordHistory(ctx context.Context, key K, op data.HistoryOperation, before []byte, after []byte) error {
+
+	entityKey, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	scope, err := c.scopeValues(ctx, GetDB(ctx))
+	if err != nil {
+		return err
+	}
+
+	row := &historyRow{
+		EntityKey:  string(entityKey),
+		Scope:      scope,
+		Operation:  string(op),
+		Actor:      c.template.GetActor(ctx),
+		ChangedAt:  time.Now(),
+		BeforeData: snapshotString(before),
+		AfterData:  snapshotString(after),
+	}
+
+	return TranslateError(GetDB(ctx).Table(c.historyTable).Create(row).Error)
+}
+
+// scopeValues returns the JSON encoding of the column values injected by the context scope, found by injecting them
+// into an empty internal entity, or an empty string if none are.
+func (c *crudTemplateImpl[E, I, K]) scopeValues(ctx context.Context, tx *gorm.DB) (string, error) {
+
+	internal := c.template.ToInternal(reflect2.ZeroInterface[E]())
+
+	sch, err := parseSchema(tx, internal)
+	if err != nil {
+		return "", err
+	}
+
+	rv := reflect.ValueOf(internal)
+	before := map[string]any{}
+
+	for _, f := range sch.Fields {
+		if f.DBName != "" {
+			before[f.DBName], _ = f.ValueOf(ctx, rv)
+		}
+	}
+
+	c.template.ApplyContextScopeValueInjector(ctx, internal, data.FetchTypeNone)
+
+	values := map[string]any{}
+
+	for _, f := range sch.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		if v, _ := f.ValueOf(ctx, rv); !reflect.DeepEqual(v, before[f.DBName]) {
+			values[f.DBName] = v
+		}
+	}
+
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded), nil
+}
+
+// recordCreated records the creation of the internal entities, which were inserted without withChanges.
+func (c *crudTemplateImpl[E, I, K]) recordCreated(ctx context.Context, tx *gorm.DB, internals []I) error {
+
+	for _, internal := range internals {
+
+		key, err := c.keyOf(ctx, tx, internal)
+		if err != nil {
+			return err
+		}
+
+		after, err := c.snapshot(ctx, key, false)
+		if err != nil {
+			return err
+		}
+
+		if err = c.recordChange(ctx, key, data.HistoryOperationCreate, nil, after); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// snapshotString returns the snapshot as a nullable column value.
+func snapshotString(snapshot []byte) *string {
+	if snapshot == nil {
+		return nil
+	}
+	s := string(snapshot)
+	return &s
+}
+
+// rawSnapshot returns the nullable column value as a snapshot.
+func rawSnapshot(value *string) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	return json.RawMessage(*value)
+}
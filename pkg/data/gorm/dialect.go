@@ -0,0 +1,389 @@
+package gorm
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const dialectPluginName = "datainfra:dialect"
+
+// Dialect provides the database specific behavior of a dialect, named by Config.Dialect. Dialects other than the
+// built-in PostgresDialect and SqliteDialect are added with RegisterDialect.
+type Dialect interface {
+	// Name returns the name of the dialect, as set in Config.Dialect.
+	Name() string
+	// Dialector returns the gorm dialector of the database of the config, such as of Config.DSN if set. Replicas are
+	// opened with a copy of the config having the host of the replica and no replica hosts.
+	Dialector(config *Config) (gorm.Dialector, error)
+	// Configure tunes the database once opened and its connection pool configured from the config.
+	Configure(db *gorm.DB, config *Config) error
+	// GooseDialect returns the goose dialect of the migrations of the database.
+	GooseDialect() string
+	// FullTextSearch returns the full text search of the dialect, or nil if full text search is not supported.
+	FullTextSearch() FullTextSearch
+	// OnConflict returns the clause of an insert resolving conflicts as described by the conflict, which is used for
+	// upserts and inserts ignoring existing rows.
+	OnConflict(conflict clause.OnConflict) clause.Expression
+	// Upsert inserts the value, or updates the row conflicting on the columns of the conflict with its updates if its
+	// Where condition holds, as a single atomic operation. Returns the number of rows inserted or updated, which is 0
+	// if the conflicting row did not meet the condition, and whether the row was inserted.
+	Upsert(tx *gorm.DB, value any, conflict clause.OnConflict) (int64, bool, error)
+	// LabelValue returns the SQL expression and argument extracting the value of a label key from a JSON labels column,
+	// or false if labels cannot be selected in SQL.
+	LabelValue(column string, key string) (string, any, bool)
+	// BeginReadOnly makes the transaction read only where the driver does not enforce the ReadOnly of sql.TxOptions,
+	// returning the function to call before the transaction ends, or nil if there is nothing to undo.
+	BeginReadOnly(tx *gorm.DB) (func() error, error)
+	// LockForUpdate returns the query locking the rows read until the transaction ends, so that concurrent
+	// transactions do not read them.
+	LockForUpdate(tx *gorm.DB) *gorm.DB
+	// LockForUpdateSkipLocked returns the query locking the rows read as LockForUpdate, skipping the rows locked by
+	// concurrent transactions instead of waiting for them.
+	LockForUpdateSkipLocked(tx *gorm.DB) *gorm.DB
+	// ChangeListener returns the listener of the changes written by the change triggers of the dialect, or nil if
+	// changes cannot be listened to.
+	ChangeListener(db *gorm.DB, config *ChangeListenerConfig) ChangeListener
+}
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = map[string]Dialect{
+		DialectPostgres: &PostgresDialect{},
+		DialectSqlite:   &SqliteDialect{},
+	}
+)
+
+// RegisterDialect registers the dialect under its name, replacing any dialect of the same name.
+func RegisterDialect(dialect Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	dialects[dialect.Name()] = dialect
+}
+
+// UnregisterDialect removes the registered dialect of the name, such as a dialect registered by a test.
+func UnregisterDialect(name string) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	delete(dialects, name)
+}
+
+// LookupDialect returns the registered dialect of the name.
+func LookupDialect(name string) (Dialect, error) {
+
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+
+	d, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown dialect %q", name)
+	}
+
+	return d, nil
+}
+
+// dialectPlugin is a gorm plugin holding the dialect with which a database was opened by NewDB.
+type dialectPlugin struct {
+	dialect Dialect
+}
+
+// Name returns the name of the plugin.
+func (d *dialectPlugin) Name() string {
+	return dialectPluginName
+}
+
+// Initialize does nothing, since the dialect is looked up by dialectOf.
+func (d *dialectPlugin) Initialize(_ *gorm.DB) error {
+	return nil
+}
+
+// dialectOf returns the dialect of the database, which is the dialect it was opened with by NewDB, or otherwise the
+// registered dialect named as its gorm dialector.
+func dialectOf(db *gorm.DB) (Dialect, error) {
+
+	if p, ok := db.Config.Plugins[dialectPluginName].(*dialectPlugin); ok {
+		return p.dialect, nil
+	}
+
+	return LookupDialect(db.Dialector.Name())
+}
+
+// onConflict returns the clause resolving conflicts as described by the conflict for the dialect of the database,
+// which is the conflict itself for databases of unknown dialects.
+func onConflict(db *gorm.DB, conflict clause.OnConflict) clause.Expression {
+
+	dialect, err := dialectOf(db)
+	if err != nil {
+		return conflict
+	}
+
+	return dialect.OnConflict(conflict)
+}
+
+// beginReadOnly makes the transaction read only with the dialect of the database, returning the function undoing it
+// or nil. Nothing is done for databases of unknown dialects.
+func beginReadOnly(tx *gorm.DB) (func() error, error) {
+
+	dialect, err := dialectOf(tx)
+	if err != nil {
+		return nil, nil
+	}
+
+	return dialect.BeginReadOnly(tx)
+}
+
+// lockForUpdate returns the query locking the rows read with the dialect of the database, or the query unchanged for
+// databases of unknown dialects.
+func lockForUpdate(tx *gorm.DB) *gorm.DB {
+
+	dialect, err := dialectOf(tx)
+	if err != nil {
+		return tx
+	}
+
+	return dialect.LockForUpdate(tx)
+}
+
+// lockForUpdateSkipLocked locks the rows read by the query with the dialect of the database, skipping the rows locked
+// by concurrent transactions, or leaves the query unchanged for databases of unknown dialects.
+func lockForUpdateSkipLocked(tx *gorm.DB) *gorm.DB {
+
+	dialect, err := dialectOf(tx)
+	if err != nil {
+		return tx
+	}
+
+	return dialect.LockForUpdateSkipLocked(tx)
+}
+
+// upsert upserts the value as described by the conflict with the dialect of the database, or for databases of unknown
+// dialects with an insert ignoring the conflict followed by the upsert.
+func upsert(tx *gorm.DB, value any, conflict clause.OnConflict) (int64, bool, error) {
+
+	dialect, err := dialectOf(tx)
+	if err != nil {
+		return upsertInsertFirst(tx, value, conflict)
+	}
+
+	return dialect.Upsert(tx, value, conflict)
+}
+
+// upsertInsertFirst upserts the value with an insert ignoring the conflict followed, if nothing was inserted, by the
+// upsert of the conflict, within a transaction. This is atomic for databases serializing their transactions.
+func upsertInsertFirst(tx *gorm.DB, value any, conflict clause.OnConflict) (int64, bool, error) {
+
+	var rows int64
+	inserted := false
+
+	err := tx.Transaction(func(tx *gorm.DB) error {
+
+		res := tx.Clauses(clause.OnConflict{Columns: conflict.Columns, DoNothing: true}).Create(value)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if rows, inserted = res.RowsAffected, res.RowsAffected > 0; inserted {
+			return nil
+		}
+
+		res = tx.Clauses(conflict).Create(value)
+		rows = res.RowsAffected
+
+		return res.Error
+	})
+
+	return rows, inserted, err
+}
+
+// PostgresDialect is the dialect of PostgreSQL databases, using the pgx driver.
+type PostgresDialect struct{}
+
+// Name returns DialectPostgres.
+func (p *PostgresDialect) Name() string {
+	return DialectPostgres
+}
+
+// Dialector returns the postgres dialector of the DSN built from the config.
+func (p *PostgresDialect) Dialector(config *Config) (gorm.Dialector, error) {
+
+	dsn := config.DSN
+	if dsn == "" {
+		dsn = postgresDSN(config, config.Host)
+	}
+
+	return postgres.New(postgres.Config{
+		DSN: dsn,
+	}), nil
+}
+
+// Configure does nothing.
+func (p *PostgresDialect) Configure(_ *gorm.DB, _ *Config) error {
+	return nil
+}
+
+// GooseDialect returns the postgres goose dialect.
+func (p *PostgresDialect) GooseDialect() string {
+	return "postgres"
+}
+
+// FullTextSearch returns the search of the tsvector column of tables.
+func (p *PostgresDialect) FullTextSearch() FullTextSearch {
+	return &postgresFullTextSearch{}
+}
+
+// OnConflict returns the conflict, which gorm renders as ON CONFLICT.
+func (p *PostgresDialect) OnConflict(conflict clause.OnConflict) clause.Expression {
+	return conflict
+}
+
+// Upsert upserts the value with a single statement returning (xmax = 0), which is true for an inserted row and false
+// for an updated row, since the update keeps the lock taken on the conflicting row. This relies on the xmax system
+// column, which postgres sets to the locking transaction on the row version written by ON CONFLICT DO UPDATE.
+// The statement is built by the create of gorm, running the hooks before create, and run through gorm as a raw query,
+// which is logged and has its error added to the transaction. Columns returned by the database are not scanned into
+// the value, and the hooks after create are not run.
+func (p *PostgresDialect) Upsert(tx *gorm.DB, value any, conflict clause.OnConflict) (int64, bool, error) {
+
+	// The create of gorm would scan the returned flag into the value, where it has no field
+	stmt := tx.Session(&gorm.Session{DryRun: true, Logger: logger.Discard}).Clauses(conflict, clause.Returning{
+		Columns: []clause.Column{{Name: "(xmax = 0)", Raw: true}},
+	}).Create(value)
+	if stmt.Error != nil {
+		return 0, false, stmt.Error
+	}
+
+	// The built statement has no ? placeholders, so its vars are passed through unchanged
+	var inserted []bool
+
+	res := tx.Raw(stmt.Statement.SQL.String(), stmt.Statement.Vars...).Scan(&inserted)
+	if res.Error != nil {
+		return 0, false, res.Error
+	}
+
+	return int64(len(inserted)), len(inserted) > 0 && inserted[0], nil
+}
+
+// LabelValue returns the ->> operator on the labels column.
+func (p *PostgresDialect) LabelValue(column string, key string) (string, any, bool) {
+	return fmt.Sprintf("%s ->> ?", column), key, true
+}
+
+// BeginReadOnly does nothing, since postgres enforces read only transactions.
+func (p *PostgresDialect) BeginReadOnly(_ *gorm.DB) (func() error, error) {
+	return nil, nil
+}
+
+// LockForUpdate locks the rows with FOR UPDATE.
+func (p *PostgresDialect) LockForUpdate(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// LockForUpdateSkipLocked locks the rows with FOR UPDATE SKIP LOCKED.
+func (p *PostgresDialect) LockForUpdateSkipLocked(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+}
+
+// ChangeListener returns the listener of the notifications on ChangeChannel.
+func (p *PostgresDialect) ChangeListener(db *gorm.DB, config *ChangeListenerConfig) ChangeListener {
+	return &postgresChangeListener{db: db, config: config}
+}
+
+// SqliteDialect is the dialect of SQLite databases, using the pure Go driver. Replicas are not supported.
+type SqliteDialect struct{}
+
+// Name returns DialectSqlite.
+func (s *SqliteDialect) Name() string {
+	return DialectSqlite
+}
+
+// Dialector returns the sqlite dialector of the file of the config, in WAL mode with foreign keys enforced.
+func (s *SqliteDialect) Dialector(config *Config) (gorm.Dialector, error) {
+
+	if len(config.ReplicaHosts) > 0 {
+		return nil, fmt.Errorf("replicas not supported for dialect %s", config.Dialect)
+	}
+
+	dsn := config.DSN
+	if dsn == "" {
+		dsn = sqliteDSN(config)
+	}
+
+	return sqlite.Open(dsn), nil
+}
+
+// Configure limits the database to a single open connection unless configured otherwise, since sqlite serializes
+// writes.
+func (s *SqliteDialect) Configure(db *gorm.DB, config *Config) error {
+
+	if config.MaxOpenConns != 0 {
+		return nil
+	}
+
+	_db, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	_db.SetMaxOpenConns(1)
+
+	return nil
+}
+
+// GooseDialect returns the sqlite3 goose dialect.
+func (s *SqliteDialect) GooseDialect() string {
+	return "sqlite3"
+}
+
+// FullTextSearch returns the search of the FTS5 tables of tables.
+func (s *SqliteDialect) FullTextSearch() FullTextSearch {
+	return &sqliteFullTextSearch{}
+}
+
+// OnConflict returns the conflict, which gorm renders as ON CONFLICT.
+func (s *SqliteDialect) OnConflict(conflict clause.OnConflict) clause.Expression {
+	return conflict
+}
+
+// Upsert upserts the value with an insert ignoring the conflict followed, if nothing was inserted, by the upsert of
+// the conflict, which is atomic since sqlite serializes writes.
+func (s *SqliteDialect) Upsert(tx *gorm.DB, value any, conflict clause.OnConflict) (int64, bool, error) {
+	return upsertInsertFirst(tx, value, conflict)
+}
+
+// LabelValue returns json_extract of the key path on the labels column.
+func (s *SqliteDialect) LabelValue(column string, key string) (string, any, bool) {
+	return fmt.Sprintf("json_extract(%s, ?)", column), fmt.Sprintf("$.%q", key), true
+}
+
+// BeginReadOnly sets query_only on the connection of the transaction until it ends, since the driver does not enforce
+// read only transactions.
+func (s *SqliteDialect) BeginReadOnly(tx *gorm.DB) (func() error, error) {
+
+	if err := tx.Exec("PRAGMA query_only = 1").Error; err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return tx.Exec("PRAGMA query_only = 0").Error
+	}, nil
+}
+
+// LockForUpdate returns the query unchanged, since sqlite serializes writes.
+func (s *SqliteDialect) LockForUpdate(tx *gorm.DB) *gorm.DB {
+	return tx
+}
+
+// LockForUpdateSkipLocked returns the query unchanged, since sqlite serializes writes.
+func (s *SqliteDialect) LockForUpdateSkipLocked(tx *gorm.DB) *gorm.DB {
+	return tx
+}
+
+// ChangeListener returns the poller of ChangeTable.
+func (s *SqliteDialect) ChangeListener(db *gorm.DB, config *ChangeListenerConfig) ChangeListener {
+	return &sqliteChangeListener{db: db, config: config}
+}
@@ -0,0 +1,96 @@
+package gorm_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	gorm2 "gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// recordingPool records the queries run on it, failing them.
+type recordingPool struct {
+	queries []string
+	args    [][]any
+}
+
+var errRecorded = errors.New("recorded")
+
+func (p *recordingPool) PrepareContext(_ context.Context, _ string) (*sql.Stmt, error) {
+	return nil, errRecorded
+}
+
+func (p *recordingPool) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	p.queries = append(p.queries, query)
+	p.args = append(p.args, args)
+	return nil, errRecorded
+}
+
+func (p *recordingPool) QueryContext(_ context.Context, query string, args ...any) (*sql.Rows, error) {
+	p.queries = append(p.queries, query)
+	p.args = append(p.args, args)
+	return nil, errRecorded
+}
+
+func (p *recordingPool) QueryRowContext(_ context.Context, _ string, _ ...any) *sql.Row {
+	return nil
+}
+
+// recordingLogger records the traced statements.
+type recordingLogger struct {
+	gormlogger.Interface
+	traced []string
+}
+
+func (l *recordingLogger) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	s, _ := fc()
+	l.traced = append(l.traced, s)
+}
+
+type upserted struct {
+	ID          string `gorm:"primaryKey"`
+	Description string
+}
+
+func TestPostgresDialect_Upsert(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	pool := &recordingPool{}
+	log := &recordingLogger{Interface: gormlogger.Discard}
+
+	db, err := gorm2.Open(postgres.New(postgres.Config{Conn: pool}), &gorm2.Config{
+		DisableAutomaticPing: true,
+		Logger:               log,
+	})
+	r.NoError(err)
+
+	dialect, err := gorm.LookupDialect(gorm.DialectPostgres)
+	r.NoError(err)
+
+	tx := db.Table("upserted")
+
+	_, _, err = dialect.Upsert(tx, &upserted{ID: "a", Description: "b"}, clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description"}),
+	})
+
+	// The statement is run, logged and has its error added through gorm
+	a.ErrorIs(err, errRecorded)
+	a.ErrorIs(tx.Error, errRecorded)
+	r.Len(pool.queries, 1)
+	a.Equal(`INSERT INTO "upserted" ("id","description") VALUES ($1,$2) ON CONFLICT ("id") DO UPDATE SET "description"="excluded"."description" RETURNING (xmax = 0)`,
+		pool.queries[0])
+	a.Equal([]any{"a", "b"}, pool.args[0])
+	r.Len(log.traced, 1)
+	a.True(strings.HasPrefix(log.traced[0], `INSERT INTO "upserted"`))
+}
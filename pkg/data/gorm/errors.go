@@ -0,0 +1,72 @@
+package gorm
+
+import (
+	"errors"
+
+	"github.com/activatedio/datainfra/pkg/data"
+)
+
+const (
+	// Postgres SQLSTATE codes
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+
+	// SQLite result codes
+	sqliteBusy                 = 5
+	sqliteLocked               = 6
+	sqliteConstraintForeignKey = 787
+	sqliteConstraintPrimaryKey = 1555
+	sqliteConstraintUnique     = 2067
+)
+
+// pgError is implemented by the errors of the Postgres driver.
+type pgError interface {
+	SQLState() string
+}
+
+// sqliteError is implemented by the errors of the SQLite driver.
+type sqliteError interface {
+	Code() int
+}
+
+// TranslateError maps database errors onto the errors of the data package, so that callers can use errors.Is
+// regardless of the dialect. The database error is kept as the cause. Errors which are not recognized are returned
+// unchanged.
+func TranslateError(err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	var pe pgError
+	if errors.As(err, &pe) {
+		switch pe.SQLState() {
+		case pgUniqueViolation:
+			return data.EntityAlreadyExists{Cause: err}
+		case pgForeignKeyViolation:
+			return data.ForeignKeyViolation{Cause: err}
+		case pgSerializationFailure, pgDeadlockDetected:
+			return data.Conflict{Cause: err}
+		}
+		return err
+	}
+
+	var se sqliteError
+	if errors.As(err, &se) {
+		switch se.Code() {
+		case sqliteConstraintUnique, sqliteConstraintPrimaryKey:
+			return data.EntityAlreadyExists{Cause: err}
+		case sqliteConstraintForeignKey:
+			return data.ForeignKeyViolation{Cause: err}
+		}
+		// Extended codes of busy and locked share the primary code in the lower 8 bits
+		switch se.Code() & 0xff {
+		case sqliteBusy, sqliteLocked:
+			return data.Conflict{Cause: err}
+		}
+	}
+
+	return err
+}
@@ -2,47 +2,82 @@ package gorm
 
 import (
 	"context"
-	"fmt"
+	"strings"
 
 	"github.com/activatedio/datainfra/pkg/data"
 )
 
 type filterKeysTemplateImpl[E any, I any, K comparable] struct {
-	template   MappingTemplate[E, I]
-	findColumn string
+	template    MappingTemplate[E, I]
+	findColumns []string
 }
 
 // MappingFilterKeysTemplateImplOptions defines options for configuring a filter keys template implementation.
-// It includes a mapping template and the column used to find entities.
+// It includes a mapping template and the column used to find entities, or the columns of a composite key, which are
+// matched in order to the fields of the key struct.
 type MappingFilterKeysTemplateImplOptions[E any, I any, K comparable] struct {
-	Template   MappingTemplate[E, I]
-	FindColumn string
+	Template    MappingTemplate[E, I]
+	FindColumn  string
+	FindColumns []string
 }
 
 // NewMappingFilterKeysTemplate creates a new filter keys template implementation for managing entity key filtering.
 // It uses the provided options, including a mapping template and a column to identify entities.
 func NewMappingFilterKeysTemplate[E any, I any, K comparable](options MappingFilterKeysTemplateImplOptions[E, I, K]) data.FilterKeysTemplate[K] {
+
+	findColumns := options.FindColumns
+	if len(findColumns) == 0 {
+		findColumns = []string{options.FindColumn}
+	}
+
 	return &filterKeysTemplateImpl[E, I, K]{
-		template:   options.Template,
-		findColumn: options.FindColumn,
+		template:    options.Template,
+		findColumns: findColumns,
 	}
 }
 
-// FilterKeys retrieves and filters a subset of input keys from the database based on the configured column and context.
+// FilterKeys retrieves and filters a subset of input keys from the database based on the configured columns and context.
 func (c *filterKeysTemplateImpl[E, I, K]) FilterKeys(ctx context.Context, keys []K) ([]K, error) {
 
+	ctx, end := instrument(ctx, c.template.GetInstrumentation(), &Operation{
+		Name:      OperationFilterKeys,
+		Table:     c.template.GetTable(),
+		FetchType: data.FetchTypeKeys,
+	})
+
+	result, err := c.filterKeys(ctx, keys)
+	end(int64(len(result)), err)
+
+	return result, err
+}
+
+// filterKeys filters the keys, as described for FilterKeys.
+func (c *filterKeysTemplateImpl[E, I, K]) filterKeys(ctx context.Context, keys []K) ([]K, error) {
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	cond, err := keysInCondition(c.findColumns, keys)
+	if err != nil {
+		return nil, err
+	}
+
 	// TODO - we may want to move this into the Template
 	tx := GetDB(ctx).Table(c.template.GetTable())
 	tx = c.template.ApplyContextScopeQueryBuilder(ctx, tx, data.FetchTypeKeys)
 
-	tx.Select(c.findColumn).Where(fmt.Sprintf("%s IN ?", c.findColumn), keys)
-
-	var result []K
-
-	tx.Find(&result)
+	rows, err := tx.Select(strings.Join(c.findColumns, ", ")).Where(cond).Rows()
+	if err != nil {
+		return nil, TranslateError(err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
 
-	if tx.Error != nil {
-		return nil, tx.Error
+	result, err := scanKeys[K](rows, len(c.findColumns))
+	if err != nil {
+		return nil, TranslateError(err)
 	}
 
 	return result, nil
@@ -0,0 +1,224 @@
+package gorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// FullTextTableSuffix is appended to the table name to form the name of the SQLite FTS5 table of a table.
+	// The FTS5 table is expected to use the table as its external content, with the rowid as the content rowid,
+	// and to be kept in sync by triggers.
+	FullTextTableSuffix = "_fts"
+	// searchScoreColumn is the alias of the relevance score in scored queries.
+	searchScoreColumn = "search_score"
+)
+
+// searchScoreType is the type of the relevance score in scored queries.
+var searchScoreType = reflect.TypeFor[float64]()
+
+// FullTextQuery is a single full text predicate value.
+type FullTextQuery struct {
+	// Value is the text of the query
+	Value string
+	// Web indicates the value is a web search style query, rather than a list of keywords
+	Web bool
+}
+
+// FullTextSearch applies full text queries to a query for a specific dialect.
+type FullTextSearch interface {
+	// Apply adds the condition matching all the queries on the table and returns the expression for the relevance
+	// score, where a higher score is more relevant.
+	Apply(tx *gorm.DB, table string, queries []FullTextQuery) (*gorm.DB, clause.Expr)
+}
+
+// fullTextSearchFor returns the full text search of the dialect of the database.
+func fullTextSearchFor(db *gorm.DB) (FullTextSearch, error) {
+
+	dialect, err := dialectOf(db)
+	if err != nil {
+		return nil, err
+	}
+
+	fts := dialect.FullTextSearch()
+	if fts == nil {
+		return nil, fmt.Errorf("full text search not supported for dialect %s", dialect.Name())
+	}
+
+	return fts, nil
+}
+
+// postgresFullTextSearch matches queries against the tsvector column of the table.
+type postgresFullTextSearch struct{}
+
+// Apply matches the tsvector column against the combined tsquery and ranks using ts_rank.
+func (p *postgresFullTextSearch) Apply(tx *gorm.DB, table string, queries []FullTextQuery) (*gorm.DB, clause.Expr) {
+
+	var parts []string
+	var args []any
+
+	for _, q := range queries {
+		if q.Web {
+			parts = append(parts, "websearch_to_tsquery('english', ?)")
+		} else {
+			parts = append(parts, "plainto_tsquery('english', ?)")
+		}
+		args = append(args, q.Value)
+	}
+
+	col := qualifyColumn(table, DefaultFullTextColumn)
+	tsQuery := fmt.Sprintf("(%s)", strings.Join(parts, " && "))
+
+	return tx.Where(fmt.Sprintf("%s @@ %s", col, tsQuery), args...), clause.Expr{
+		SQL:  fmt.Sprintf("ts_rank(%s, %s)", col, tsQuery),
+		Vars: args,
+	}
+}
+
+// sqliteFullTextSearch matches queries against the FTS5 table of the table.
+type sqliteFullTextSearch struct{}
+
+// Apply joins the FTS5 table, matches the combined FTS5 query and ranks using bm25.
+func (s *sqliteFullTextSearch) Apply(tx *gorm.DB, table string, queries []FullTextQuery) (*gorm.DB, clause.Expr) {
+
+	var parts []string
+
+	for _, q := range queries {
+
+		var part string
+		if q.Web {
+			part = sqliteWebSearchQuery(q.Value)
+		} else {
+			part = sqliteKeywordsQuery(q.Value)
+		}
+
+		if part == "" {
+			// Like an empty tsquery, matches nothing
+			return tx.Where("1 = 0"), clause.Expr{SQL: "0"}
+		}
+
+		parts = append(parts, fmt.Sprintf("(%s)", part))
+	}
+
+	ftsTable := table + FullTextTableSuffix
+
+	tx = tx.Joins(fmt.Sprintf("JOIN %s ON %s.rowid = %s.rowid", ftsTable, ftsTable, table)).
+		Where(fmt.Sprintf("%s MATCH ?", ftsTable), strings.Join(parts, " AND "))
+
+	// bm25 returns lower values for better matches
+	return tx, clause.Expr{
+		SQL: fmt.Sprintf("-bm25(%s)", ftsTable),
+	}
+}
+
+// ftsWords splits the text into the words which are indexed by the FTS5 unicode61 tokenizer.
+func ftsWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ftsPhrase returns the words as a quoted FTS5 phrase, or blank if there are no words.
+func ftsPhrase(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\"%s\"", strings.Join(words, " "))
+}
+
+// sqliteKeywordsQuery translates keywords to an FTS5 query matching all the keywords, as plainto_tsquery does.
+func sqliteKeywordsQuery(keywords string) string {
+
+	words := ftsWords(keywords)
+	terms := make([]string, len(words))
+
+	for i, w := range words {
+		terms[i] = ftsPhrase([]string{w})
+	}
+
+	return strings.Join(terms, " AND ")
+}
+
+// sqliteWebSearchQuery translates a web search style query to an FTS5 query, following websearch_to_tsquery.
+// Unquoted words are all required, quoted text is matched as a phrase, "or" between terms matches either term
+// and a leading "-" excludes the term. A query with only excluded terms matches nothing.
+func sqliteWebSearchQuery(query string) string {
+
+	// Each group is a list of alternatives which are combined with OR
+	var groups [][]string
+	var excluded []string
+
+	orPending := false
+	rs := []rune(query)
+
+	for i := 0; i < len(rs); {
+
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+
+		negated := false
+		if rs[i] == '-' {
+			negated = true
+			i++
+		}
+
+		var term string
+
+		if i < len(rs) && rs[i] == '"' {
+			end := i + 1
+			for end < len(rs) && rs[end] != '"' {
+				end++
+			}
+			term = ftsPhrase(ftsWords(string(rs[i+1 : min(end, len(rs))])))
+			i = end + 1
+		} else {
+			end := i
+			for end < len(rs) && !unicode.IsSpace(rs[end]) && rs[end] != '"' {
+				end++
+			}
+			word := string(rs[i:end])
+			i = end
+			if !negated && strings.EqualFold(word, "or") && len(groups) > 0 {
+				orPending = true
+				continue
+			}
+			term = ftsPhrase(ftsWords(word))
+		}
+
+		switch {
+		case term == "":
+		case negated:
+			excluded = append(excluded, term)
+		case orPending:
+			groups[len(groups)-1] = append(groups[len(groups)-1], term)
+		default:
+			groups = append(groups, []string{term})
+		}
+
+		orPending = false
+	}
+
+	if len(groups) == 0 {
+		return ""
+	}
+
+	ands := make([]string, len(groups))
+	for i, g := range groups {
+		ands[i] = fmt.Sprintf("(%s)", strings.Join(g, " OR "))
+	}
+
+	res := fmt.Sprintf("(%s)", strings.Join(ands, " AND "))
+
+	for _, e := range excluded {
+		res = fmt.Sprintf("%s NOT %s", res, e)
+	}
+
+	return res
+}
@@ -3,13 +3,11 @@ package gorm
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/activatedio/datainfra/pkg/data"
-	"github.com/glebarez/sqlite"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 const (
@@ -19,55 +17,112 @@ const (
 	DialectSqlite = "sqlite"
 )
 
-// NewDB creates and configures a new Gorm database instance based on the provided DBConfig.
+// NewDB creates and configures a new Gorm database instance based on the provided DBConfig, with the registered
+// dialect named by the config. Reads are routed to the replica hosts, if any, as by ReadFromReplica. The database
+// is closed with CloseDB, which also closes the replicas.
 func NewDB(config *Config) (*gorm.DB, error) {
 
-	var dialector gorm.Dialector
-
-	switch config.Dialect {
-
-	case DialectPostgres:
-		dialector = postgres.New(postgres.Config{
-			DSN: fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s",
-				config.Host, config.Port, config.Username, config.Password, config.Name),
-		})
-	case DialectSqlite:
-		dialector = sqlite.Open(fmt.Sprintf("%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", config.Name))
-	default:
-		panic("unexpected dialect " + config.Dialect)
+	if config.DSN != "" && len(config.ReplicaHosts) > 0 {
+		return nil, fmt.Errorf("replicas not supported with a DSN")
 	}
 
-	db, err := gorm.Open(dialector, &gorm.Config{
-		SkipDefaultTransaction: !config.EnableDefaultTransaction,
-		Logger: func() logger.Interface {
-			if config.EnableSQLLogging {
-				return logger.Default.LogMode(logger.Info)
-			}
-			return nil
-		}(),
-	})
+	dialect, err := LookupDialect(config.Dialect)
+	if err != nil {
+		return nil, err
+	}
 
+	db, err := openDB(dialect, config)
 	if err != nil {
 		return nil, err
 	}
 
-	switch config.Dialect {
-	case DialectPostgres:
-	case DialectSqlite:
-		var _db *sql.DB
-		_db, err = db.DB()
-		if err != nil {
-			return nil, err
+	var replicaDBs []*gorm.DB
+
+	for _, host := range config.ReplicaHosts {
+
+		replicaConfig := *config
+		replicaConfig.Host = host
+		replicaConfig.ReplicaHosts = nil
+
+		var rdb *gorm.DB
+		if rdb, err = openDB(dialect, &replicaConfig); err != nil {
+			return nil, errors.Join(fmt.Errorf("replica %s: %w", host, err), closeDBs(append(replicaDBs, db)...))
 		}
-		_db.SetMaxOpenConns(1)
-	default:
-		panic("unexpected dialect " + config.Dialect)
+		replicaDBs = append(replicaDBs, rdb)
+	}
+
+	if err = RegisterReplicas(db, replicaDBs...); err != nil {
+		return nil, errors.Join(err, closeDBs(append(replicaDBs, db)...))
 	}
 
 	return db, err
 
 }
 
+// openDB opens the database of the config with the dialect, and configures its connection pool.
+func openDB(dialect Dialect, config *Config) (*gorm.DB, error) {
+
+	dialector, err := dialect.Dialector(config)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, gormConfig(config))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Use(&dialectPlugin{dialect: dialect}); err != nil {
+		return nil, err
+	}
+
+	if err = registerLogTable(db); err != nil {
+		return nil, err
+	}
+
+	if err = configurePool(db, config); err != nil {
+		return nil, err
+	}
+
+	if err = dialect.Configure(db, config); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// configurePool applies the connection pool settings of the config to the database.
+func configurePool(db *gorm.DB, config *Config) error {
+
+	_db, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	if config.MaxOpenConns > 0 {
+		_db.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns != 0 {
+		_db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime > 0 {
+		_db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime > 0 {
+		_db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+
+	return nil
+}
+
+// gormConfig returns the gorm configuration of the config.
+func gormConfig(config *Config) *gorm.Config {
+	return &gorm.Config{
+		SkipDefaultTransaction: !config.EnableDefaultTransaction,
+		Logger:                 NewLogger(config),
+	}
+}
+
 type contextKey struct {
 	name string
 }
@@ -76,6 +131,10 @@ var dbKey = contextKey{
 	name: "db",
 }
 
+var txOptionsKey = contextKey{
+	name: "txOptions",
+}
+
 // WithDB returns a new context with the provided *gorm.DB instance stored in it under a specific key.
 func WithDB(ctx context.Context, db *gorm.DB) context.Context {
 	return context.WithValue(ctx, dbKey, db)
@@ -97,7 +156,8 @@ func NewContextBuilder(db *gorm.DB) data.ContextBuilder {
 	}
 }
 
-// GetDB retrieves the *gorm.DB instance from the provided context.
+// GetDB retrieves the *gorm.DB instance from the provided context, bound to the context for cancellation.
+// Within InTx, this is the transaction. Queries go to the primary, unless routed to a replica by ReadFromReplica.
 // Panics if the database instance is not found in the context.
 func GetDB(ctx context.Context) *gorm.DB {
 
@@ -105,5 +165,67 @@ func GetDB(ctx context.Context) *gorm.DB {
 	if !ok {
 		panic("DB not in context")
 	}
-	return tx
+	return tx.WithContext(ctx)
+}
+
+// InTx runs fn within a transaction on the database in the context. The context passed to fn carries the
+// transaction. If the context already carries a transaction, a savepoint is used, and any options must match those of
+// the outer transaction, otherwise data.ErrNestedTxOptions is returned.
+func InTx(ctx context.Context, fn func(ctx context.Context) error, opts ...data.TxOption) error {
+
+	db := GetDB(ctx)
+	o := data.NewTxOptions(opts...)
+
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+
+		outer, ok := ctx.Value(txOptionsKey).(*data.TxOptions)
+		if !ok {
+			outer = &data.TxOptions{}
+		}
+
+		if len(opts) > 0 && *o != *outer {
+			return fmt.Errorf("%w: %+v, outer %+v", data.ErrNestedTxOptions, *o, *outer)
+		}
+
+		// Nested, gorm uses a savepoint
+		return db.Transaction(func(tx *gorm.DB) error {
+			return fn(WithDB(ctx, tx))
+		})
+	}
+
+	ctx = context.WithValue(ctx, txOptionsKey, o)
+
+	return db.Transaction(func(tx *gorm.DB) (err error) {
+
+		if o.ReadOnly {
+			var reset func() error
+			if reset, err = beginReadOnly(tx); err != nil {
+				return err
+			}
+			if reset != nil {
+				defer func() {
+					if resetErr := reset(); err == nil {
+						err = resetErr
+					}
+				}()
+			}
+		}
+
+		return fn(WithDB(ctx, tx))
+	}, &sql.TxOptions{
+		Isolation: o.Isolation,
+		ReadOnly:  o.ReadOnly,
+	})
+}
+
+type txManager struct{}
+
+// InTx runs fn within a transaction carried by the context passed to fn.
+func (t *txManager) InTx(ctx context.Context, fn func(ctx context.Context) error, opts ...data.TxOption) error {
+	return InTx(ctx, fn, opts...)
+}
+
+// NewTxManager initializes and returns a TxManager using the database in the context.
+func NewTxManager() data.TxManager {
+	return &txManager{}
 }
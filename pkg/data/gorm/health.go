@@ -0,0 +1,339 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultHealthCheckInterval is the default interval at which the health of the database is checked.
+	DefaultHealthCheckInterval = 10 * time.Second
+	// DefaultHealthCheckTimeout is the default maximum time of a health check.
+	DefaultHealthCheckTimeout = 5 * time.Second
+)
+
+// ErrNotReady indicates the database has not yet been found ready by a health check.
+var ErrNotReady = errors.New("database not ready")
+
+// HealthConfig is the configuration of the health checker.
+type HealthConfig struct {
+	// Interval is the interval at which the health is checked while the application is running. Defaults to
+	// DefaultHealthCheckInterval.
+	Interval time.Duration
+	// Timeout is the maximum time of a health check. Defaults to DefaultHealthCheckTimeout.
+	Timeout time.Duration
+}
+
+// MigrationHealth is the version of a set of migrations in the database.
+type MigrationHealth struct {
+	// Name is the name of the MigratorData of the migrations.
+	Name string
+	// CurrentVersion is the latest version applied to the database, or -1 if it could not be read.
+	CurrentVersion int64
+	// ExpectedVersion is the version of the latest migration of the set.
+	ExpectedVersion int64
+}
+
+// ReplicaHealth is the health of a replica of the database.
+type ReplicaHealth struct {
+	// PoolStats are the statistics of the connection pool of the replica.
+	PoolStats sql.DBStats
+	// Err is the error of the ping of the replica, or nil if it was reachable.
+	Err error
+}
+
+// Health is the result of a health check of the database.
+type Health struct {
+	// CheckedAt is the time of the check.
+	CheckedAt time.Time
+	// PoolStats are the statistics of the connection pool of the database.
+	PoolStats sql.DBStats
+	// Replicas are the health of the replicas, in the order registered.
+	Replicas []*ReplicaHealth
+	// Migrations are the versions of the migrations, in the order of the MigratorData.
+	Migrations []*MigrationHealth
+	// Err is the error of the check, or nil if the database is ready.
+	Err error
+}
+
+// Ready reports whether the database and its replicas were reachable and its migrations were at the expected
+// versions.
+func (h *Health) Ready() bool {
+	return h.Err == nil
+}
+
+// HealthChecker checks that the database and its replicas are reachable and that its migrations are at the expected
+// versions, such as to report readiness. While the application is running, the health is checked periodically.
+type HealthChecker interface {
+	// Check pings the database and its replicas and reads the versions of the migrations, returning the health
+	// found. Migrations are at the expected version when the latest version applied is at least the version of the
+	// latest migration, so that a database migrated by a newer release is ready.
+	Check(ctx context.Context) *Health
+	// Health returns the health of the latest check, or nil before the first check.
+	Health() *Health
+	// Ready returns nil if the latest check found the database ready, otherwise the error of the check, or
+	// ErrNotReady before the first check.
+	Ready() error
+}
+
+type healthChecker struct {
+	db       *gorm.DB
+	data     []MigratorData
+	interval time.Duration
+	timeout  time.Duration
+	lock     sync.RWMutex
+	health   *Health
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// HealthCheckerParams are the parameters for NewHealthChecker.
+type HealthCheckerParams struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	DB        *gorm.DB
+	Data      []MigratorData `optional:"true"`
+	Config    *HealthConfig  `optional:"true"`
+}
+
+// NewHealthChecker creates a HealthChecker of the database and the migrations of the MigratorData. The health is
+// checked as the application starts, and then periodically until it stops. A failed check does not fail the start,
+// so that readiness is reported once the database is ready.
+func NewHealthChecker(params HealthCheckerParams) HealthChecker {
+
+	h := &healthChecker{
+		db:       params.DB,
+		data:     params.Data,
+		interval: DefaultHealthCheckInterval,
+		timeout:  DefaultHealthCheckTimeout,
+	}
+
+	if cfg := params.Config; cfg != nil {
+		if cfg.Interval > 0 {
+			h.interval = cfg.Interval
+		}
+		if cfg.Timeout > 0 {
+			h.timeout = cfg.Timeout
+		}
+	}
+
+	params.Lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			h.checkAndLog(ctx)
+			h.start()
+			return nil
+		},
+		OnStop: func(_ context.Context) error {
+			h.stop()
+			return nil
+		},
+	})
+
+	return h
+}
+
+// HealthModule provides a HealthChecker checking the database while the application is running. Requires a
+// *gorm.DB, and optionally the []MigratorData and a *HealthConfig.
+func HealthModule() fx.Option {
+	return fx.Module("datainfra.data.gorm.health", fx.Provide(NewHealthChecker), fx.Invoke(func(HealthChecker) {}))
+}
+
+// Check pings the database and its replicas and reads the versions of the migrations.
+func (h *healthChecker) Check(ctx context.Context) *Health {
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	res := &Health{
+		CheckedAt: time.Now(),
+	}
+
+	db, err := h.db.DB()
+	if err != nil {
+		res.Err = err
+		return h.record(res)
+	}
+
+	res.PoolStats = db.Stats()
+
+	if err = db.PingContext(ctx); err != nil {
+		res.Err = fmt.Errorf("ping: %w", err)
+		return h.record(res)
+	}
+
+	var errs []error
+
+	for i, rdb := range replicasOf(h.db) {
+		rh := checkReplica(ctx, rdb)
+		res.Replicas = append(res.Replicas, rh)
+		if rh.Err != nil {
+			errs = append(errs, fmt.Errorf("replica %d: %w", i, rh.Err))
+		}
+	}
+
+	for _, d := range h.data {
+		mh, mErr := h.checkMigrations(ctx, db, d)
+		res.Migrations = append(res.Migrations, mh)
+		if mErr != nil {
+			errs = append(errs, fmt.Errorf("migrations %s: %w", d.Name, mErr))
+		}
+	}
+
+	res.Err = errors.Join(errs...)
+
+	return h.record(res)
+}
+
+// checkReplica pings the replica, returning its health.
+func checkReplica(ctx context.Context, rdb *gorm.DB) *ReplicaHealth {
+
+	res := &ReplicaHealth{}
+
+	db, err := rdb.DB()
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	res.PoolStats = db.Stats()
+
+	if err = db.PingContext(ctx); err != nil {
+		res.Err = fmt.Errorf("ping: %w", err)
+	}
+
+	return res
+}
+
+// checkMigrations reads the current and expected versions of the migrations, returning an error if the migrations
+// are behind.
+func (h *healthChecker) checkMigrations(ctx context.Context, db *sql.DB, d MigratorData) (*MigrationHealth, error) {
+
+	res := &MigrationHealth{
+		Name:            d.Name,
+		CurrentVersion:  -1,
+		ExpectedVersion: -1,
+	}
+
+	dialect, err := dialectOf(h.db)
+	if err != nil {
+		return res, err
+	}
+
+	fsys, err := fs.Sub(d.FS, d.Path)
+	if err != nil {
+		return res, err
+	}
+
+	// The provider only collects the migrations, since it creates a missing version table as it reads the version
+	provider, err := goose.NewProvider(goose.Dialect(dialect.GooseDialect()), db, fsys)
+	if err != nil {
+		return res, err
+	}
+
+	for _, source := range provider.ListSources() {
+		res.ExpectedVersion = max(res.ExpectedVersion, source.Version)
+	}
+
+	store, err := database.NewStore(database.Dialect(dialect.GooseDialect()), d.VersionTable())
+	if err != nil {
+		return res, err
+	}
+
+	current, err := store.GetLatestVersion(ctx, db)
+	if err != nil {
+		return res, err
+	}
+
+	res.CurrentVersion = current
+
+	if res.CurrentVersion < res.ExpectedVersion {
+		return res, fmt.Errorf("at version %d, expected %d", res.CurrentVersion, res.ExpectedVersion)
+	}
+
+	return res, nil
+}
+
+// record records the health as that of the latest check.
+func (h *healthChecker) record(health *Health) *Health {
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.health = health
+
+	return health
+}
+
+// Health returns the health of the latest check.
+func (h *healthChecker) Health() *Health {
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return h.health
+}
+
+// Ready returns the error of the latest check.
+func (h *healthChecker) Ready() error {
+
+	health := h.Health()
+
+	if health == nil {
+		return ErrNotReady
+	}
+
+	return health.Err
+}
+
+// checkAndLog checks the health, logging when the database is not ready.
+func (h *healthChecker) checkAndLog(ctx context.Context) {
+	if health := h.Check(ctx); !health.Ready() {
+		log.Warn().Err(health.Err).Msg("database not ready")
+	}
+}
+
+// start starts checking the health periodically until stopped.
+func (h *healthChecker) start() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+
+	h.wg.Add(1)
+
+	go func() {
+
+		defer h.wg.Done()
+
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			h.checkAndLog(ctx)
+		}
+	}()
+}
+
+// stop stops checking the health, waiting for the current check to complete.
+func (h *healthChecker) stop() {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.wg.Wait()
+}
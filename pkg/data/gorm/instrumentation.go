@@ -0,0 +1,130 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data"
+)
+
+const (
+	// OperationDoFind is the operation of MappingTemplate.DoFind, and of the finds of CRUD templates.
+	OperationDoFind = "DoFind"
+	// OperationDoList is the operation of MappingTemplate.DoList, and of the lists of CRUD templates.
+	OperationDoList = "DoList"
+	// OperationCreate is the operation of creating an entity.
+	OperationCreate = "Create"
+	// OperationUpdate is the operation of updating an entity.
+	OperationUpdate = "Update"
+	// OperationDelete is the operation of deleting an entity.
+	OperationDelete = "Delete"
+	// OperationFilterKeys is the operation of filtering keys to those of existing entities.
+	OperationFilterKeys = "FilterKeys"
+	// OperationAssociate is the operation of adding and removing the children associated with a parent.
+	OperationAssociate = "Associate"
+	// OperationSearch is the operation of searching entities.
+	OperationSearch = "Search"
+)
+
+const (
+	// ErrorClassNotFound classifies data.ErrNotFound errors.
+	ErrorClassNotFound = "not_found"
+	// ErrorClassAlreadyExists classifies data.ErrAlreadyExists errors.
+	ErrorClassAlreadyExists = "already_exists"
+	// ErrorClassConflict classifies data.ErrConflict, data.ErrStaleVersion and data.ErrForeignKeyViolation errors.
+	ErrorClassConflict = "conflict"
+	// ErrorClassScopeViolation classifies data.ErrScopeViolation errors.
+	ErrorClassScopeViolation = "scope_violation"
+	// ErrorClassInvalid classifies data.ErrInvalidPredicate and data.ErrInvalidFieldMask errors.
+	ErrorClassInvalid = "invalid"
+	// ErrorClassCanceled classifies errors of canceled contexts.
+	ErrorClassCanceled = "canceled"
+	// ErrorClassTimeout classifies errors of contexts past their deadline.
+	ErrorClassTimeout = "timeout"
+	// ErrorClassInternal classifies all other errors.
+	ErrorClassInternal = "internal"
+)
+
+// Operation describes an instrumented repository operation.
+type Operation struct {
+	// Name is the name of the operation, such as OperationCreate.
+	Name string
+	// Table is the table of the entities of the operation, or the association table for OperationAssociate.
+	Table string
+	// FetchType is the fetch type of reads, or data.FetchTypeNone for writes.
+	FetchType data.FetchType
+}
+
+// OperationResult is the outcome of an instrumented repository operation.
+type OperationResult struct {
+	// Rows is the number of entities or keys read, 1 for a successful write of an entity, or the number of keys added
+	// and removed for OperationAssociate.
+	Rows int64
+	// Latency is the duration of the operation.
+	Latency time.Duration
+	// Err is the error of the operation, if it failed.
+	Err error
+	// ErrorClass is the ErrorClass of Err, or blank if the operation succeeded.
+	ErrorClass string
+}
+
+// Instrumentation observes repository operations, such as to emit spans and metrics.
+type Instrumentation interface {
+	// Start is called as the operation starts, and returns the context of the operation, such as one carrying its
+	// span, and the function called with the result once the operation ends.
+	Start(ctx context.Context, op *Operation) (context.Context, func(result *OperationResult))
+}
+
+// NoopInstrumentation is an Instrumentation which does nothing, used when none is configured.
+type NoopInstrumentation struct{}
+
+// Start returns the context unchanged.
+func (n NoopInstrumentation) Start(ctx context.Context, _ *Operation) (context.Context, func(result *OperationResult)) {
+	return ctx, func(_ *OperationResult) {}
+}
+
+// ErrorClass returns the class of the error, such as ErrorClassNotFound, or blank if the error is nil.
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, data.ErrNotFound):
+		return ErrorClassNotFound
+	case errors.Is(err, data.ErrAlreadyExists):
+		return ErrorClassAlreadyExists
+	case errors.Is(err, data.ErrConflict), errors.Is(err, data.ErrForeignKeyViolation):
+		return ErrorClassConflict
+	case errors.Is(err, data.ErrScopeViolation):
+		return ErrorClassScopeViolation
+	case errors.Is(err, data.ErrInvalidPredicate), errors.Is(err, data.ErrInvalidFieldMask):
+		return ErrorClassInvalid
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	default:
+		return ErrorClassInternal
+	}
+}
+
+// instrument starts the operation with the instrumentation, if any, and returns the context of the operation and the
+// function ending it with the number of rows and the error of the operation.
+func instrument(ctx context.Context, instrumentation Instrumentation, op *Operation) (context.Context, func(rows int64, err error)) {
+
+	if instrumentation == nil {
+		return ctx, func(_ int64, _ error) {}
+	}
+
+	begin := time.Now()
+	ctx, end := instrumentation.Start(ctx, op)
+
+	return ctx, func(rows int64, err error) {
+		end(&OperationResult{
+			Rows:       rows,
+			Latency:    time.Since(begin),
+			Err:        err,
+			ErrorClass: ErrorClass(err),
+		})
+	}
+}
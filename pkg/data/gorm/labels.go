@@ -0,0 +1,73 @@
+package gorm
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// labelValueExpr returns the SQL expression and argument to extract the value of a label key from a JSON labels column,
+// as provided by the dialect of the database.
+func labelValueExpr(db *gorm.DB, column string, key string) (string, any, bool) {
+
+	dialect, err := dialectOf(db)
+	if err != nil {
+		return "", nil, false
+	}
+
+	return dialect.LabelValue(column, key)
+}
+
+// ApplyLabelSelector translates the requirements of the selector into conditions on the JSON labels column.
+// Requirements which cannot be translated, such as numeric comparisons or requirements on an unsupported dialect,
+// are returned as a selector to be applied in memory. The returned selector is nil if all requirements were translated.
+func ApplyLabelSelector(tx *gorm.DB, column string, sel labels.Selector) (*gorm.DB, labels.Selector) {
+
+	if sel == nil || sel.Empty() {
+		return tx, nil
+	}
+
+	reqs, selectable := sel.Requirements()
+
+	if !selectable {
+		// Selects nothing
+		return tx.Where("1 = 0"), nil
+	}
+
+	var remaining []labels.Requirement
+
+	for _, r := range reqs {
+
+		expr, keyArg, ok := labelValueExpr(tx, column, r.Key())
+
+		if !ok {
+			remaining = append(remaining, r)
+			continue
+		}
+
+		switch r.Operator() {
+		case selection.Equals, selection.DoubleEquals:
+			tx = tx.Where(fmt.Sprintf("%s = ?", expr), keyArg, r.Values().List()[0])
+		case selection.NotEquals:
+			tx = tx.Where(fmt.Sprintf("(%s IS NULL OR %s <> ?)", expr, expr), keyArg, keyArg, r.Values().List()[0])
+		case selection.In:
+			tx = tx.Where(fmt.Sprintf("%s IN ?", expr), keyArg, r.Values().List())
+		case selection.NotIn:
+			tx = tx.Where(fmt.Sprintf("(%s IS NULL OR %s NOT IN ?)", expr, expr), keyArg, keyArg, r.Values().List())
+		case selection.Exists:
+			tx = tx.Where(fmt.Sprintf("%s IS NOT NULL", expr), keyArg)
+		case selection.DoesNotExist:
+			tx = tx.Where(fmt.Sprintf("%s IS NULL", expr), keyArg)
+		default:
+			remaining = append(remaining, r)
+		}
+	}
+
+	if len(remaining) == 0 {
+		return tx, nil
+	}
+
+	return tx, labels.NewSelector().Add(remaining...)
+}
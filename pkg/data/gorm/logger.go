@@ -0,0 +1,169 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	// DefaultSlowQueryThreshold is the default duration above which queries are logged as slow.
+	DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+	logTableCallbackName = "datainfra:log_table"
+)
+
+var logTableKey = contextKey{
+	name: "logTable",
+}
+
+// zerologLogger is a gorm logger writing to the zerolog logger of the context, or the global logger if there is none.
+type zerologLogger struct {
+	level         logger.LogLevel
+	slowThreshold time.Duration
+	redact        bool
+}
+
+// NewLogger creates a gorm logger writing to zerolog, as configured by the logging settings of the config. Failed and
+// slow queries are logged, as are all queries with EnableSQLLogging, along with the request ID of the context and the
+// table of the query.
+func NewLogger(config *Config) logger.Interface {
+
+	l := &zerologLogger{
+		level:         logger.Warn,
+		slowThreshold: DefaultSlowQueryThreshold,
+		redact:        config.RedactSQLParameters,
+	}
+
+	if config.EnableSQLLogging {
+		l.level = logger.Info
+	}
+
+	if config.SlowQueryThreshold != 0 {
+		l.slowThreshold = config.SlowQueryThreshold
+	}
+
+	return l
+}
+
+// LogMode returns a copy of the logger logging at the level.
+func (l *zerologLogger) LogMode(level logger.LogLevel) logger.Interface {
+	c := *l
+	c.level = level
+	return &c
+}
+
+// Info logs the message at info level.
+func (l *zerologLogger) Info(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Info {
+		contextLogger(ctx).Info().Msgf(msg, args...)
+	}
+}
+
+// Warn logs the message at warn level.
+func (l *zerologLogger) Warn(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Warn {
+		contextLogger(ctx).Warn().Msgf(msg, args...)
+	}
+}
+
+// Error logs the message at error level.
+func (l *zerologLogger) Error(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Error {
+		contextLogger(ctx).Error().Msgf(msg, args...)
+	}
+}
+
+// Trace logs the query, at error level if it failed other than by not finding a record, at warn level if it is
+// slow, and otherwise at info level.
+func (l *zerologLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	zl := contextLogger(ctx)
+
+	var e *zerolog.Event
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		e = zl.Error().Err(err)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		e = zl.Warn().Bool("slow", true)
+	case l.level >= logger.Info:
+		e = zl.Info()
+	default:
+		return
+	}
+
+	sql, rows := fc()
+
+	e = e.Str("sql", sql).Dur("elapsed", elapsed)
+
+	if rows >= 0 {
+		e = e.Int64("rows", rows)
+	}
+	if table, ok := ctx.Value(logTableKey).(string); ok {
+		e = e.Str("table", table)
+	}
+	if id := data.GetRequestID(ctx); id != "" {
+		e = e.Str("request_id", id)
+	}
+
+	e.Msg("query")
+}
+
+// ParamsFilter removes the parameters of queries when redacting, so that queries are logged with their placeholders.
+func (l *zerologLogger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if l.redact {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// contextLogger returns the zerolog logger of the context, or the global logger if there is none.
+func contextLogger(ctx context.Context) *zerolog.Logger {
+	if ctx != nil {
+		if zl := zerolog.Ctx(ctx); zl != zerolog.DefaultContextLogger && zl.GetLevel() != zerolog.Disabled {
+			return zl
+		}
+	}
+	return &log.Logger
+}
+
+// registerLogTable registers callbacks adding the table of each query to its context, so that it is logged with the
+// query.
+func registerLogTable(db *gorm.DB) error {
+
+	fn := func(tx *gorm.DB) {
+		if tx.Statement.Table != "" && tx.Statement.Context != nil {
+			tx.Statement.Context = context.WithValue(tx.Statement.Context, logTableKey, tx.Statement.Table)
+		}
+	}
+
+	cb := db.Callback()
+
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register(logTableCallbackName, fn),
+		cb.Query().Before("gorm:query").Register(logTableCallbackName, fn),
+		cb.Update().Before("gorm:update").Register(logTableCallbackName, fn),
+		cb.Delete().Before("gorm:delete").Register(logTableCallbackName, fn),
+		cb.Row().Before("gorm:row").Register(logTableCallbackName, fn),
+		cb.Raw().Before("gorm:raw").Register(logTableCallbackName, fn),
+	} {
+		if err != nil {
+			return fmt.Errorf("register log table callback: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package gorm
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// MigratorData represents a migration configuration including its name, file system, and relative path.
+type MigratorData struct {
+	Name string
+	FS   fs.FS
+	Path string
+}
+
+// VersionTable returns the goose table recording the versions of the migrations applied.
+func (m MigratorData) VersionTable() string {
+	return fmt.Sprintf("goose_migration_%s", m.Name)
+}
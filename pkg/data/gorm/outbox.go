@@ -0,0 +1,333 @@
+package gorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultOutboxTable is the default table of the outbox.
+	DefaultOutboxTable = "outbox"
+	// DefaultOutboxPollInterval is the default interval at which the relay polls the outbox.
+	DefaultOutboxPollInterval = time.Second
+	// DefaultOutboxBatchSize is the default number of events the relay reads from the outbox at a time.
+	DefaultOutboxBatchSize = 100
+	// DefaultOutboxClaimTimeout is the default time after which the events claimed by a relay may be claimed again.
+	DefaultOutboxClaimTimeout = time.Minute
+)
+
+// outboxRow is a row of an outbox table.
+type outboxRow struct {
+	ID         int64      `gorm:"column:id;primaryKey;autoIncrement"`
+	EntityType string     `gorm:"column:entity_type"`
+	EntityKey  string     `gorm:"column:entity_key"`
+	Operation  string     `gorm:"column:operation"`
+	Payload    *string    `gorm:"column:payload"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	ClaimedAt  *time.Time `gorm:"column:claimed_at"`
+}
+
+// appendEvent inserts an event for the change of the entity with the key into the outbox table.
+func (c *crudTemplateImpl[E, I, K]) appendEvent(ctx context.Context, key K, op data.HistoryOperation, before []byte, after []byte) error {
+
+	entityKey, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	payload := after
+	if op == data.HistoryOperationDelete {
+		payload = before
+	}
+
+	entityType := c.entityType
+	if entityType == "" {
+		entityType = c.template.GetTable()
+	}
+
+	row := &outboxRow{
+		EntityType: entityType,
+		EntityKey:  string(entityKey),
+		Operation:  string(op),
+		Payload:    snapshotString(payload),
+		CreatedAt:  time.Now(),
+	}
+
+	return TranslateError(GetDB(ctx).Table(c.outboxTable).Create(row).Error)
+}
+
+// OutboxConfig is the configuration of the outbox relay.
+type OutboxConfig struct {
+	// Table is the outbox table. Defaults to DefaultOutboxTable.
+	Table string
+	// PollInterval is the interval at which the outbox is polled. Defaults to DefaultOutboxPollInterval.
+	PollInterval time.Duration
+	// BatchSize is the number of events read at a time. Defaults to DefaultOutboxBatchSize.
+	BatchSize int
+	// ClaimTimeout is the time after which claimed events which are neither published nor released, such as those of
+	// a relay which stopped, may be claimed again. It must exceed the time to publish a batch. Defaults to
+	// DefaultOutboxClaimTimeout.
+	ClaimTimeout time.Duration
+}
+
+// OutboxRelay publishes the events appended to the outbox by CRUD templates to the data.Publisher, removing them from
+// the outbox once published. Events are delivered at least once, since an event published before a failure to remove
+// it is published again once its claim expires.
+type OutboxRelay interface {
+	// RelayOnce publishes a single batch of events, oldest first, and returns the number of events published. When an
+	// event fails to be published, the later events of the same entity are held back so that the events of an entity
+	// are published in order, and the errors are returned once the published events are removed.
+	RelayOnce(ctx context.Context) (int, error)
+}
+
+type outboxRelay struct {
+	db           *gorm.DB
+	publisher    data.Publisher
+	table        string
+	pollInterval time.Duration
+	batchSize    int
+	claimTimeout time.Duration
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// OutboxRelayParams are the parameters for NewOutboxRelay.
+type OutboxRelayParams struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	DB        *gorm.DB
+	Publisher data.Publisher
+	Config    *OutboxConfig `optional:"true"`
+}
+
+// NewOutboxRelay creates an OutboxRelay which polls the outbox while the application is running.
+func NewOutboxRelay(params OutboxRelayParams) OutboxRelay {
+
+	r := &outboxRelay{
+		db:           params.DB,
+		publisher:    params.Publisher,
+		table:        DefaultOutboxTable,
+		pollInterval: DefaultOutboxPollInterval,
+		batchSize:    DefaultOutboxBatchSize,
+		claimTimeout: DefaultOutboxClaimTimeout,
+	}
+
+	if cfg := params.Config; cfg != nil {
+		if cfg.Table != "" {
+			r.table = cfg.Table
+		}
+		if cfg.PollInterval > 0 {
+			r.pollInterval = cfg.PollInterval
+		}
+		if cfg.BatchSize > 0 {
+			r.batchSize = cfg.BatchSize
+		}
+		if cfg.ClaimTimeout > 0 {
+			r.claimTimeout = cfg.ClaimTimeout
+		}
+	}
+
+	params.Lifecycle.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			r.start()
+			return nil
+		},
+		OnStop: func(_ context.Context) error {
+			r.stop()
+			return nil
+		},
+	})
+
+	return r
+}
+
+// OutboxModule provides an OutboxRelay polling the outbox while the application is running. Requires a *gorm.DB and
+// a data.Publisher, and optionally an *OutboxConfig.
+func OutboxModule() fx.Option {
+	return fx.Module("datainfra.data.gorm.outbox", fx.Provide(NewOutboxRelay), fx.Invoke(func(OutboxRelay) {}))
+}
+
+// start starts polling the outbox until stopped.
+func (r *outboxRelay) start() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+
+	go func() {
+
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// Full batches are followed immediately by the next batch
+			for ctx.Err() == nil {
+				n, err := r.RelayOnce(ctx)
+				if err != nil {
+					log.Warn().Err(err).Str("table", r.table).Msg("outbox relay")
+					break
+				}
+				if n < r.batchSize {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// stop stops polling the outbox, waiting for the current batch to complete.
+func (r *outboxRelay) stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// RelayOnce publishes a single batch of events. The batch is claimed in a short transaction, so that concurrent relays
+// do not publish the same events, and the events are published after it commits. Published events are then removed and
+// the others released.
+func (r *outboxRelay) RelayOnce(ctx context.Context) (int, error) {
+
+	rows, err := r.claim(ctx)
+	if err != nil {
+		return 0, TranslateError(err)
+	}
+
+	var publishErrs []error
+	var published []int64
+	var released []int64
+
+	// Entities with an event which failed to be published
+	held := map[string]bool{}
+
+	for _, row := range rows {
+
+		entity := row.EntityType + "\x00" + row.EntityKey
+
+		if held[entity] {
+			released = append(released, row.ID)
+			continue
+		}
+
+		if err := r.publisher.Publish(ctx, row.event()); err != nil {
+			held[entity] = true
+			released = append(released, row.ID)
+			publishErrs = append(publishErrs, fmt.Errorf("publish of event %d: %w", row.ID, err))
+			continue
+		}
+
+		published = append(published, row.ID)
+	}
+
+	db := r.db.WithContext(ctx)
+
+	if len(published) > 0 {
+		if err := db.Table(r.table).Where("id IN ?", published).Delete(&outboxRow{}).Error; err != nil {
+			return 0, TranslateError(err)
+		}
+	}
+
+	if len(released) > 0 {
+		if err := db.Table(r.table).Where("id IN ?", released).Update("claimed_at", nil).Error; err != nil {
+			return len(published), errors.Join(append(publishErrs, TranslateError(err))...)
+		}
+	}
+
+	return len(published), errors.Join(publishErrs...)
+}
+
+// claim claims the oldest events which are not claimed, skipping those locked by concurrent relays where the dialect
+// supports it. Events with an earlier event of the same entity outside the batch are left unclaimed, so that the
+// events of an entity are published in order.
+func (r *outboxRelay) claim(ctx context.Context) ([]*outboxRow, error) {
+
+	var claimed []*outboxRow
+
+	err := InTx(WithDB(ctx, r.db), func(ctx context.Context) error {
+
+		now := time.Now().UTC()
+
+		var rows []*outboxRow
+
+		tx := lockForUpdateSkipLocked(GetDB(ctx).Table(r.table))
+
+		if err := tx.Where("claimed_at IS NULL OR claimed_at < ?", now.Add(-r.claimTimeout)).
+			Order("id").Limit(r.batchSize).Find(&rows).Error; err != nil {
+			return err
+		}
+
+		if len(rows) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, len(rows))
+		for i, row := range rows {
+			ids[i] = row.ID
+		}
+
+		var blocked []int64
+
+		if err := GetDB(ctx).Table(r.table+" AS e").Where("e.id IN ?", ids).
+			Where(fmt.Sprintf("EXISTS (SELECT 1 FROM %s AS p WHERE p.entity_type = e.entity_type "+
+				"AND p.entity_key = e.entity_key AND p.id < e.id AND p.id NOT IN ?)", r.table), ids).
+			Pluck("e.id", &blocked).Error; err != nil {
+			return err
+		}
+
+		isBlocked := map[int64]bool{}
+		for _, id := range blocked {
+			isBlocked[id] = true
+		}
+
+		var claimedIDs []int64
+
+		for _, row := range rows {
+			if !isBlocked[row.ID] {
+				claimed = append(claimed, row)
+				claimedIDs = append(claimedIDs, row.ID)
+			}
+		}
+
+		if len(claimedIDs) == 0 {
+			return nil
+		}
+
+		return GetDB(ctx).Table(r.table).Where("id IN ?", claimedIDs).Update("claimed_at", now).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+// event returns the event of the row.
+func (r *outboxRow) event() *data.Event {
+	return &data.Event{
+		ID:         r.ID,
+		EntityType: r.EntityType,
+		EntityKey:  r.EntityKey,
+		Operation:  data.HistoryOperation(r.Operation),
+		Payload:    rawSnapshot(r.Payload),
+		CreatedAt:  r.CreatedAt,
+	}
+}
@@ -0,0 +1,425 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DefaultPageCount is the number of entries returned in a page when PageParams.Count is not set.
+	DefaultPageCount = 100
+	// MaxPageCount is the maximum number of entries returned in a single page.
+	MaxPageCount = 1000
+)
+
+// orderTerm is a single term of the ordering used for keyset pagination.
+type orderTerm struct {
+	// Column is the column name used to read the value of the term from a returned row.
+	Column string
+	// Expr is the SQL expression used for ordering and for comparing against the cursor.
+	Expr string
+	// Args are the arguments of the placeholders in Expr.
+	Args []any
+	// Desc indicates the term is sorted in descending order.
+	Desc bool
+	// Nullable indicates the expression may be NULL, which is sorted after all values, so last in ascending order and
+	// first in descending order.
+	Nullable bool
+	// Type is the type of the values of the expression, as which they are decoded from the cursor, such as a
+	// time.Time encoded as text. Without it, numbers are decoded as int64 when integral.
+	Type reflect.Type
+}
+
+// cursor is the decoded representation of a page token.
+type cursor struct {
+	// Order identifies the ordering the cursor was created with.
+	Order string `json:"o"`
+	// Values are the values of the order terms for the last row of the previous page.
+	Values []any `json:"v"`
+}
+
+// orderSignature returns a string which identifies the ordering of the terms.
+func orderSignature(terms []orderTerm) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		if t.Desc {
+			parts[i] = t.Column + " desc"
+		} else {
+			parts[i] = t.Column
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// encodeCursor encodes the cursor into an opaque page token.
+func encodeCursor(c *cursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor decodes an opaque page token into a cursor, validating it against the number of order terms.
+func decodeCursor(token string, terms []orderTerm) (*cursor, error) {
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid page token")
+	}
+
+	// Values are decoded once their terms are known
+	var raw struct {
+		Order  string            `json:"o"`
+		Values []json.RawMessage `json:"v"`
+	}
+
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return nil, errors.Wrap(err, "invalid page token")
+	}
+
+	if len(raw.Values) != len(terms) {
+		return nil, errors.New("invalid page token")
+	}
+
+	if raw.Order != orderSignature(terms) {
+		return nil, errors.New("page token does not match the sort order")
+	}
+
+	c := &cursor{
+		Order:  raw.Order,
+		Values: make([]any, len(raw.Values)),
+	}
+
+	for i, v := range raw.Values {
+		if c.Values[i], err = terms[i].decode(v); err != nil {
+			return nil, errors.Wrap(err, "invalid page token")
+		}
+	}
+
+	return c, nil
+}
+
+// decode decodes a value of the term from its encoding in a cursor, as a value of the type of the term if set.
+func (t orderTerm) decode(raw json.RawMessage) (any, error) {
+
+	if t.Type == nil {
+
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+
+		var v any
+		if err := d.Decode(&v); err != nil {
+			return nil, err
+		}
+
+		if n, ok := v.(json.Number); ok {
+			return fromJSONNumber(n), nil
+		}
+
+		return v, nil
+	}
+
+	if bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	v := reflect.New(t.Type)
+	if err := json.Unmarshal(raw, v.Interface()); err != nil {
+		return nil, err
+	}
+
+	return v.Elem().Interface(), nil
+}
+
+// fromJSONNumber converts a number to an int64 when it is integral, to avoid loss of precision on keys.
+func fromJSONNumber(n json.Number) any {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n.String()
+}
+
+// page holds the state of a single keyset paginated query.
+type page struct {
+	count  int
+	terms  []orderTerm
+	cursor *cursor
+}
+
+// newPage creates a page from the page parameters, applying the default and maximum counts.
+func newPage(pageParams *data.PageParams, terms []orderTerm) (*page, error) {
+
+	p := &page{
+		count: DefaultPageCount,
+		terms: terms,
+	}
+
+	if pageParams == nil {
+		return p, nil
+	}
+
+	switch {
+	case pageParams.Count < 0:
+		return nil, fmt.Errorf("invalid page count %d", pageParams.Count)
+	case pageParams.Count > MaxPageCount:
+		p.count = MaxPageCount
+	case pageParams.Count > 0:
+		p.count = pageParams.Count
+	}
+
+	if pageParams.PageToken != "" {
+		c, err := decodeCursor(pageParams.PageToken, terms)
+		if err != nil {
+			return nil, err
+		}
+		p.cursor = c
+	}
+
+	return p, nil
+}
+
+// apply adds the ordering, the keyset condition and the limit to the query. One row more than the page count
+// is requested to determine if there is a next page.
+func (p *page) apply(tx *gorm.DB) *gorm.DB {
+
+	// A single expression is used since gorm ignores order columns once an order expression is set
+	var orders []string
+	var orderArgs []any
+
+	for _, t := range p.terms {
+		switch {
+		case t.Nullable && t.Desc:
+			orders = append(orders, t.Expr+" DESC NULLS FIRST")
+		case t.Nullable:
+			orders = append(orders, t.Expr+" NULLS LAST")
+		case t.Desc:
+			orders = append(orders, t.Expr+" DESC")
+		default:
+			orders = append(orders, t.Expr)
+		}
+		orderArgs = append(orderArgs, t.Args...)
+	}
+
+	tx = tx.Clauses(clause.OrderBy{
+		Expression: clause.Expr{
+			SQL:                strings.Join(orders, ", "),
+			Vars:               orderArgs,
+			WithoutParentheses: true,
+		},
+	})
+
+	if p.cursor != nil {
+
+		// Expands to (a > ?) OR (a = ? AND b > ?) OR ... so that mixed sort directions are supported
+		var ors []string
+		var args []any
+
+		for i, t := range p.terms {
+
+			after, afterArgs, ok := t.after(p.cursor.Values[i])
+			if !ok {
+				continue
+			}
+
+			var ands []string
+
+			for j := 0; j < i; j++ {
+				eq, eqArgs := p.terms[j].equal(p.cursor.Values[j])
+				ands = append(ands, eq)
+				args = append(args, eqArgs...)
+			}
+
+			ands = append(ands, after)
+			args = append(args, afterArgs...)
+
+			ors = append(ors, fmt.Sprintf("(%s)", strings.Join(ands, " AND ")))
+		}
+
+		if len(ors) == 0 {
+			ors = append(ors, "1 = 0")
+		}
+
+		tx = tx.Where(fmt.Sprintf("(%s)", strings.Join(ors, " OR ")), args...)
+	}
+
+	return tx.Limit(p.count + 1)
+}
+
+// equal returns the condition and arguments matching the value of the term.
+func (t orderTerm) equal(value any) (string, []any) {
+	if t.Nullable && isNil(value) {
+		return fmt.Sprintf("%s IS NULL", t.Expr), t.Args
+	}
+	return fmt.Sprintf("%s = ?", t.Expr), append(slices.Clone(t.Args), value)
+}
+
+// after returns the condition and arguments matching the values sorted after the value of the term, or false if there
+// are none, which is the case of NULL in ascending order.
+func (t orderTerm) after(value any) (string, []any, bool) {
+
+	op := ">"
+	if t.Desc {
+		op = "<"
+	}
+
+	args := append(slices.Clone(t.Args), value)
+
+	switch {
+	case !t.Nullable:
+		return fmt.Sprintf("%s %s ?", t.Expr, op), args, true
+	case isNil(value) && t.Desc:
+		return fmt.Sprintf("%s IS NOT NULL", t.Expr), t.Args, true
+	case isNil(value):
+		return "", nil, false
+	case t.Desc:
+		return fmt.Sprintf("%s < ?", t.Expr), args, true
+	default:
+		return fmt.Sprintf("(%s > ? OR %s IS NULL)", t.Expr, t.Expr), append(args, t.Args...), true
+	}
+}
+
+// isNil returns whether the value is nil or a nil pointer.
+func isNil(value any) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// completePage trims the overflow row from the results and produces the token for the next page, if any.
+func completePage[R any](ctx context.Context, sch *schema.Schema, p *page, rows []R) ([]R, string, error) {
+
+	if len(rows) <= p.count {
+		return rows, "", nil
+	}
+
+	rows = rows[:p.count]
+
+	c := &cursor{
+		Order: orderSignature(p.terms),
+	}
+
+	last := reflect.ValueOf(rows[len(rows)-1])
+
+	for _, t := range p.terms {
+		f := sch.LookUpField(t.Column)
+		if f == nil {
+			return nil, "", fmt.Errorf("column %s not found in %s", t.Column, sch.Name)
+		}
+		v, _ := f.ValueOf(ctx, last)
+		c.Values = append(c.Values, v)
+	}
+
+	token, err := encodeCursor(c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return rows, token, nil
+}
+
+// sortOrderTerms returns the order terms for the sort criteria, followed by ascending terms for the key columns
+// which are not already sorted on, so that the ordering is unique. Fields must be key columns or in the sortable list.
+// Sortable fields of pointer types are nullable, while NULL values of other fields are sorted as the zero value of the
+// field, which is the value read into the entity.
+func sortOrderTerms(table string, sch *schema.Schema, sortable []string, keyColumns []string, sort []data.SortCriterion) ([]orderTerm, error) {
+
+	terms := make([]orderTerm, 0, len(sort)+len(keyColumns))
+	seen := map[string]bool{}
+
+	for _, sc := range sort {
+
+		isKey := slices.Contains(keyColumns, sc.Field)
+
+		if !slices.Contains(sortable, sc.Field) && !isKey {
+			return nil, fmt.Errorf("field %s is not sortable", sc.Field)
+		}
+
+		if seen[sc.Field] {
+			return nil, fmt.Errorf("field %s is sorted more than once", sc.Field)
+		}
+		seen[sc.Field] = true
+
+		term := orderTerm{
+			Column: sc.Field,
+			Expr:   qualifyColumn(table, sc.Field),
+			Desc:   sc.Direction == data.SortDirectionDescending,
+		}
+
+		f := sch.LookUpField(sc.Field)
+		if f == nil {
+			return nil, fmt.Errorf("column %s not found in %s", sc.Field, sch.Name)
+		}
+
+		term.Type = valueType(f)
+
+		if !isKey {
+			switch {
+			case f.FieldType.Kind() == reflect.Pointer:
+				term.Nullable = true
+			case !f.NotNull && !f.PrimaryKey:
+				term.Expr = fmt.Sprintf("COALESCE(%s, ?)", term.Expr)
+				term.Args = []any{reflect.Zero(f.FieldType).Interface()}
+			}
+		}
+
+		terms = append(terms, term)
+	}
+
+	for _, k := range keyColumns {
+		if !seen[k] {
+			term := orderTerm{
+				Column: k,
+				Expr:   qualifyColumn(table, k),
+			}
+			if f := sch.LookUpField(k); f != nil {
+				term.Type = valueType(f)
+			}
+			terms = append(terms, term)
+		}
+	}
+
+	return terms, nil
+}
+
+// valueType returns the type of the values of the field, which is the type pointed to for fields of pointer types.
+func valueType(f *schema.Field) reflect.Type {
+	if f.FieldType.Kind() == reflect.Pointer {
+		return f.FieldType.Elem()
+	}
+	return f.FieldType
+}
+
+// qualifyColumn prefixes the column with the table name unless it is already qualified.
+func qualifyColumn(table, column string) string {
+	if strings.Contains(column, ".") {
+		return column
+	}
+	return fmt.Sprintf("%s.%s", table, column)
+}
+
+// parseSchema parses the gorm schema of the provided model using the naming strategy and cache of the database.
+func parseSchema(db *gorm.DB, model any) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
@@ -0,0 +1,77 @@
+package gorm_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pagedEvent struct {
+	ID         int64 `gorm:"primaryKey"`
+	OccurredAt time.Time
+}
+
+func TestDoList_SortByTimestamp(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	db, err := gorm.NewDB(&gorm.Config{
+		Dialect: gorm.DialectSqlite,
+		Name:    filepath.Join(t.TempDir(), "paging"),
+	})
+	r.NoError(err)
+
+	r.NoError(db.Exec("CREATE TABLE paged_events (id INTEGER PRIMARY KEY, occurred_at TIMESTAMP NOT NULL)").Error)
+
+	// Timestamps are out of key order, with fractional seconds and a tie
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	offsets := []time.Duration{
+		3 * time.Second, 500 * time.Millisecond, time.Hour, 1500 * time.Millisecond, 3 * time.Second, 0,
+	}
+
+	for i, o := range offsets {
+		r.NoError(db.Create(&pagedEvent{ID: int64(i + 1), OccurredAt: start.Add(o)}).Error)
+	}
+
+	unit := gorm.NewTemplate[*pagedEvent](gorm.TemplateParams[*pagedEvent, *pagedEvent]{
+		Table:          "paged_events",
+		SortableFields: []string{"occurred_at"},
+	})
+
+	ctx := gorm.WithDB(context.Background(), db)
+
+	for _, dir := range []data.SortDirection{data.SortDirectionAscending, data.SortDirectionDescending} {
+
+		var ids []int64
+		token := ""
+
+		// Pages are bounded, since a page repeating rows would never end
+		for range len(offsets) {
+			got, err := unit.DoList(ctx, nil, data.ListParams{
+				Sort:       []data.SortCriterion{{Field: "occurred_at", Direction: dir}},
+				PageParams: &data.PageParams{Count: 2, PageToken: token},
+			})
+			r.NoError(err)
+
+			for _, e := range got.List {
+				ids = append(ids, e.ID)
+			}
+
+			if token = got.NextPageToken; token == "" {
+				break
+			}
+		}
+
+		if dir == data.SortDirectionAscending {
+			a.Equal([]int64{6, 2, 4, 1, 5, 3}, ids)
+		} else {
+			a.Equal([]int64{3, 1, 5, 4, 2, 6}, ids)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	"gorm.io/gorm"
+)
+
+const replicasPluginName = "datainfra:replicas"
+
+// replicas is a gorm plugin holding the replicas of a database and their connection pools.
+type replicas struct {
+	dbs   []*gorm.DB
+	pools []gorm.ConnPool
+	next  atomic.Uint64
+}
+
+// Name returns the name of the plugin.
+func (r *replicas) Name() string {
+	return replicasPluginName
+}
+
+// Initialize does nothing, since queries are routed by ReadFromReplica.
+func (r *replicas) Initialize(_ *gorm.DB) error {
+	return nil
+}
+
+// pick returns the pool of the next replica, in turn.
+func (r *replicas) pick() gorm.ConnPool {
+	return r.pools[(r.next.Add(1)-1)%uint64(len(r.pools))]
+}
+
+// RegisterReplicas registers replicas of the primary database, to which ReadFromReplica routes reads.
+func RegisterReplicas(db *gorm.DB, replicaDBs ...*gorm.DB) error {
+
+	if len(replicaDBs) == 0 {
+		return nil
+	}
+
+	r := &replicas{}
+
+	for _, rdb := range replicaDBs {
+		r.dbs = append(r.dbs, rdb)
+		r.pools = append(r.pools, rdb.Statement.ConnPool)
+	}
+
+	return db.Use(r)
+}
+
+// replicasOf returns the replicas registered on the database, if any.
+func replicasOf(db *gorm.DB) []*gorm.DB {
+	if r, ok := db.Config.Plugins[replicasPluginName].(*replicas); ok {
+		return r.dbs
+	}
+	return nil
+}
+
+// CloseDB closes the database and the replicas registered on it, such as a database created by NewDB.
+func CloseDB(db *gorm.DB) error {
+	return closeDBs(append([]*gorm.DB{db}, replicasOf(db)...)...)
+}
+
+// closeDBs closes the databases, returning the errors joined.
+func closeDBs(dbs ...*gorm.DB) error {
+
+	var errs []error
+
+	for _, db := range dbs {
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ReadFromReplica routes the query to a replica of its database, if any are registered, for reads of the fetch types
+// FetchTypeList, FetchTypeDetail and FetchTypeKeys. Other queries, queries within a transaction and queries in a
+// context marked with data.WithPrimary go to the primary.
+func ReadFromReplica(ctx context.Context, tx *gorm.DB, fetchType data.FetchType) *gorm.DB {
+
+	switch fetchType {
+	case data.FetchTypeList, data.FetchTypeDetail, data.FetchTypeKeys:
+	default:
+		return tx
+	}
+
+	if data.IsWithPrimary(ctx) {
+		return tx
+	}
+
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return tx
+	}
+
+	r, ok := tx.Config.Plugins[replicasPluginName].(*replicas)
+	if !ok {
+		return tx
+	}
+
+	// The statement is copied, which a session only does with a context, so that the query passed in keeps its pool
+	tx = tx.Session(&gorm.Session{Context: tx.Statement.Context})
+	tx.Statement.ConnPool = r.pick()
+
+	return tx
+}
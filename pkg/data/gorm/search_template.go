@@ -2,33 +2,43 @@ package gorm
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/activatedio/datainfra/pkg/data"
-	"github.com/pkg/errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// SearchPredicateKeywords is the name of the full text predicate matching all the provided keywords.
+	SearchPredicateKeywords = "@keywords"
+	// SearchPredicateQuery is the name of the full text predicate accepting a web search style query.
+	SearchPredicateQuery = "@query"
+	// DefaultFullTextColumn is the column used for full text predicates.
+	DefaultFullTextColumn = "full_text"
 )
 
 type searchTemplateImpl[E any, I any] struct {
 	template         MappingTemplate[E, I]
 	searchPredicates []*data.SearchPredicateDescriptor
+	predicateColumns map[string]string
 }
 
 // GetSearchPredicates retrieves a list of search predicate descriptors defined for the current search template.
 func (c *searchTemplateImpl[E, I]) GetSearchPredicates(_ context.Context) ([]*data.SearchPredicateDescriptor, error) {
-	// TODO - this is begging for some constants
 	return c.searchPredicates, nil
 }
 
-// GetSearchPredicateDescriptor retrieves a descriptor for a search predicate by name, supporting only "keywords".
+// GetSearchPredicateDescriptor retrieves the descriptor for a search predicate by name.
 func (c *searchTemplateImpl[E, I]) GetSearchPredicateDescriptor(_ context.Context, name string) (*data.SearchPredicateDescriptor, error) {
-	if name != "keywords" {
-		return nil, errors.New("only keywords supported")
+	for _, d := range c.searchPredicates {
+		if d.Name == name {
+			return d, nil
+		}
 	}
-	return &data.SearchPredicateDescriptor{
-		Name:      "keywords",
-		Label:     "Keywords",
-		Operators: []data.SearchOperator{data.SearchOperatorStringMatch},
-	}, nil
+	return nil, data.InvalidPredicate{Name: name, Reason: "not supported"}
 }
 
 // MappingSearchTemplateParams defines parameters required to create a mapping search template.
@@ -36,6 +46,8 @@ func (c *searchTemplateImpl[E, I]) GetSearchPredicateDescriptor(_ context.Contex
 type MappingSearchTemplateParams[E any, I any] struct {
 	Template         MappingTemplate[E, I]
 	SearchPredicates []*data.SearchPredicateDescriptor
+	// PredicateColumns maps predicate names to columns. Predicates which are not mapped use their name as the column.
+	PredicateColumns map[string]string
 }
 
 // NewMappingSearchTemplate creates a new search template with specified mapping and search predicates.
@@ -45,6 +57,7 @@ func NewMappingSearchTemplate[E any, I any](params MappingSearchTemplateParams[E
 	return &searchTemplateImpl[E, I]{
 		template:         params.Template,
 		searchPredicates: params.SearchPredicates,
+		predicateColumns: params.PredicateColumns,
 	}
 }
 
@@ -55,37 +68,156 @@ type SearchTemplateParams[E any] struct {
 	SearchPredicates []*data.SearchPredicateDescriptor
 }
 
-// Search performs a search based on the specified criteria and pagination parameters, returning a list of search results.
-func (c *searchTemplateImpl[E, I]) Search(ctx context.Context, criteria []*data.SearchPredicate, pageParams *data.PageParams) (*data.List[*data.SearchResult[E]], error) {
+// Search performs a search based on the specified criteria and list parameters, returning a list of search results.
+func (c *searchTemplateImpl[E, I]) Search(ctx context.Context, criteria []*data.SearchPredicate, params data.ListParams) (res *data.List[*data.SearchResult[E]], err error) {
+
+	ctx, end := instrument(ctx, c.template.GetInstrumentation(), &Operation{
+		Name:      OperationSearch,
+		Table:     c.template.GetTable(),
+		FetchType: data.FetchTypeList,
+	})
+	defer func() {
+		if res != nil {
+			end(int64(len(res.List)), err)
+		} else {
+			end(0, err)
+		}
+	}()
 
-	// Right now we only support a single predicate of name "keywords"
-	if len(criteria) != 1 || criteria[0].Name != "keywords" || criteria[0].Operator != data.SearchOperatorStringMatch {
-		return nil, errors.New("only single predicate of keywords supported")
+	for _, p := range criteria {
+		if err = c.validatePredicate(ctx, p); err != nil {
+			return nil, err
+		}
 	}
 
-	keywords := criteria[0].StringValue
+	return c.template.DoListScored(ctx, c.criteriaBuilder(criteria), params)
+}
 
-	got, err := c.template.DoList(ctx, func(tx *gorm.DB) *gorm.DB {
-		return tx.Where("full_text @@ plainto_tsquery('english', ?)", keywords)
-	}, data.ListParams{
-		PageParams: pageParams,
-	})
+// CountSearch returns the number of results of the search across all pages, ignoring paging and sorting.
+func (c *searchTemplateImpl[E, I]) CountSearch(ctx context.Context, criteria []*data.SearchPredicate, params data.ListParams) (int64, error) {
 
-	if err != nil {
-		return nil, err
+	for _, p := range criteria {
+		if err := c.validatePredicate(ctx, p); err != nil {
+			return 0, err
+		}
 	}
 
-	result := make([]*data.SearchResult[E], len(got.List))
+	builder := c.criteriaBuilder(criteria)
 
-	for i, e := range got.List {
-		result[i] = &data.SearchResult[E]{
-			Score:  0,
-			Entity: e,
+	return c.template.DoCount(ctx, func(tx *gorm.DB) *gorm.DB {
+		tx, _ = builder(tx)
+		return tx
+	}, params)
+}
+
+// criteriaBuilder returns the criteria builder applying the predicates, which also provides the expression of the
+// relevance score for full text predicates.
+func (c *searchTemplateImpl[E, I]) criteriaBuilder(criteria []*data.SearchPredicate) func(tx *gorm.DB) (*gorm.DB, clause.Expr) {
+	return func(tx *gorm.DB) (*gorm.DB, clause.Expr) {
+
+		var queries []FullTextQuery
+
+		for _, p := range criteria {
+			if isFullTextPredicate(p.Name) {
+				queries = append(queries, FullTextQuery{
+					Value: p.StringValue,
+					Web:   p.Name == SearchPredicateQuery,
+				})
+				continue
+			}
+			tx = c.applyPredicate(tx, p)
+		}
+
+		if len(queries) == 0 {
+			return tx, clause.Expr{}
+		}
+
+		fts, err := fullTextSearchFor(tx)
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx, clause.Expr{}
 		}
+
+		return fts.Apply(tx, c.template.GetTable(), queries)
 	}
+}
 
-	return &data.List[*data.SearchResult[E]]{
-		List: result,
-	}, nil
+// validatePredicate checks the predicate is declared and its operator is allowed by the descriptor.
+func (c *searchTemplateImpl[E, I]) validatePredicate(ctx context.Context, p *data.SearchPredicate) error {
+
+	if p == nil {
+		return data.InvalidPredicate{Reason: "predicate is nil"}
+	}
+
+	d, err := c.GetSearchPredicateDescriptor(ctx, p.Name)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(d.Operators, p.Operator) {
+		return data.InvalidPredicate{Name: p.Name, Reason: fmt.Sprintf("operator %d not supported", p.Operator)}
+	}
+
+	if isFullTextPredicate(p.Name) && p.Operator != data.SearchOperatorStringMatch {
+		return data.InvalidPredicate{Name: p.Name, Reason: "only the match operator is supported"}
+	}
+
+	return nil
+}
+
+// isFullTextPredicate returns true for the predicates which are matched against the full text column.
+func isFullTextPredicate(name string) bool {
+	return name == SearchPredicateKeywords || name == SearchPredicateQuery
+}
+
+// column returns the column mapped to the predicate name.
+func (c *searchTemplateImpl[E, I]) column(name string) string {
+	if col, ok := c.predicateColumns[name]; ok {
+		return col
+	}
+	return name
+}
+
+// applyPredicate adds the condition for a validated predicate, other than a full text predicate, to the query.
+func (c *searchTemplateImpl[E, I]) applyPredicate(tx *gorm.DB, p *data.SearchPredicate) *gorm.DB {
+
+	col := c.column(p.Name)
+
+	switch p.Operator {
+	case data.SearchOperatorStringEquals:
+		return tx.Where(fmt.Sprintf("%s = ?", col), p.StringValue)
+	case data.SearchOperatorStringNotEquals:
+		return tx.Where(fmt.Sprintf("%s <> ?", col), p.StringValue)
+	case data.SearchOperatorStringMatch:
+		return tx.Where(fmt.Sprintf("LOWER(%s) LIKE ? ESCAPE '\\'", col), "%"+escapeLike(strings.ToLower(p.StringValue))+"%")
+	case data.SearchOperatorStringIn:
+		if len(p.StringArrayValue) == 0 {
+			// Matches nothing
+			return tx.Where("1 = 0")
+		}
+		return tx.Where(fmt.Sprintf("%s IN ?", col), p.StringArrayValue)
+	case data.SearchOperatorStringNotIn:
+		if len(p.StringArrayValue) == 0 {
+			return tx
+		}
+		return tx.Where(fmt.Sprintf("%s NOT IN ?", col), p.StringArrayValue)
+	case data.SearchOperatorNumberEquals:
+		return tx.Where(fmt.Sprintf("%s = ?", col), p.NumberValue)
+	case data.SearchOperatorNumberNotEquals:
+		return tx.Where(fmt.Sprintf("%s <> ?", col), p.NumberValue)
+	case data.SearchOperatorNumberIn:
+		if len(p.NumberArrayValue) == 0 {
+			// Matches nothing
+			return tx.Where("1 = 0")
+		}
+		return tx.Where(fmt.Sprintf("%s IN ?", col), p.NumberArrayValue)
+	default:
+		_ = tx.AddError(fmt.Errorf("unsupported search operator %d", p.Operator))
+		return tx
+	}
+}
 
+// escapeLike escapes the wildcard characters of a LIKE pattern.
+func escapeLike(v string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(v)
 }
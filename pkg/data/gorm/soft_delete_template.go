@@ -0,0 +1,95 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/activatedio/datainfra/pkg/data"
+)
+
+type softDeleteTemplateImpl[E any, I any, K comparable] struct {
+	template    MappingTemplate[E, I]
+	findBuilder FindBuilder[K]
+	changes     *crudTemplateImpl[E, I, K]
+}
+
+// MappingSoftDeleteTemplateImplOptions provides configuration options for creating a mapping-based soft delete
+// template implementation. The template must have a soft delete column.
+type MappingSoftDeleteTemplateImplOptions[E any, I any, K comparable] struct {
+	// Template specifies the mapping template used for conversions between external and internal representations.
+	Template MappingTemplate[E, I]
+	// FindBuilder is the query builder function for locating entities by key.
+	FindBuilder FindBuilder[K]
+	// HistoryTable is an optional table recording each restore and purge of an entity, as for the CRUD template.
+	HistoryTable string
+	// OutboxTable is an optional table to which an event is appended for each restore and purge of an entity, as for
+	// the CRUD template.
+	OutboxTable string
+	// EntityType is the entity type of the events appended to the outbox. Defaults to the table of the template.
+	EntityType string
+}
+
+// NewMappingSoftDeleteTemplate creates a soft delete template using a mapping template and find builder.
+func NewMappingSoftDeleteTemplate[E any, I any, K comparable](options MappingSoftDeleteTemplateImplOptions[E, I, K]) data.SoftDeleteTemplate[K] {
+
+	if options.Template.GetSoftDeleteColumn() == "" {
+		panic(fmt.Sprintf("soft delete column not set for table %s", options.Template.GetTable()))
+	}
+
+	return &softDeleteTemplateImpl[E, I, K]{
+		template:    options.Template,
+		findBuilder: options.FindBuilder,
+		changes: newCrudTemplateImpl(MappingCrudTemplateImplOptions[E, I, K]{
+			Template:     options.Template,
+			FindBuilder:  options.FindBuilder,
+			HistoryTable: options.HistoryTable,
+			OutboxTable:  options.OutboxTable,
+			EntityType:   options.EntityType,
+		}),
+	}
+}
+
+// Restore clears the soft delete column of the entity with the key, within the context scope, and records it as a
+// restore. Returns data.EntityNotFound if no soft deleted entity with the key exists in the scope.
+func (s *softDeleteTemplateImpl[E, I, K]) Restore(ctx context.Context, key K) error {
+	return s.changes.withChanges(ctx, fixedKey(key), func(ctx context.Context) (data.HistoryOperation, error) {
+		return data.HistoryOperationRestore, s.restore(ctx, key)
+	})
+}
+
+// restore clears the soft delete column, as described for Restore.
+func (s *softDeleteTemplateImpl[E, I, K]) restore(ctx context.Context, key K) error {
+
+	table := s.template.GetTable()
+	col := s.template.GetSoftDeleteColumn()
+
+	db := GetDB(ctx).Table(table)
+	db = s.template.ApplyContextScopeQueryBuilder(data.WithDeleted(ctx), db, data.FetchTypeNone)
+	db = s.findBuilder(ctx, db, key).Where(fmt.Sprintf("%s IS NOT NULL", qualifyColumn(table, col))).
+		UpdateColumn(col, nil)
+
+	switch {
+	case db.Error != nil:
+		return TranslateError(db.Error)
+	case db.RowsAffected == 0:
+		return data.EntityNotFound{}
+	default:
+		return nil
+	}
+}
+
+// Purge permanently removes the entity with the key, within the context scope, whether or not it is soft deleted. The
+// purge of an entity which is not soft deleted is recorded as a delete, while that of a soft deleted entity records
+// nothing, since its soft delete was recorded, unless the context includes soft deleted entities.
+func (s *softDeleteTemplateImpl[E, I, K]) Purge(ctx context.Context, key K) error {
+	return s.changes.withChanges(ctx, fixedKey(key), func(ctx context.Context) (data.HistoryOperation, error) {
+		return data.HistoryOperationDelete, s.purge(ctx, key)
+	})
+}
+
+// purge removes the entity, as described for Purge.
+func (s *softDeleteTemplateImpl[E, I, K]) purge(ctx context.Context, key K) error {
+	db := GetDB(ctx).Table(s.template.GetTable())
+	db = s.template.ApplyContextScopeQueryBuilder(data.WithDeleted(ctx), db, data.FetchTypeNone)
+	return TranslateError(s.findBuilder(ctx, db, key).Delete(new(E)).Error)
+}
@@ -4,24 +4,46 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/activatedio/datainfra/pkg/data"
 	"github.com/activatedio/datainfra/pkg/reflect"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // MappingTemplate defines operations for mapping between external and internal representations of entities.
 type MappingTemplate[E any, I any] interface {
 	// GetTable returns the name of the database table associated with the template.
 	GetTable() string
-	// ApplyContextScopeQueryBuilder applies context-based query modifications to the database query.
+	// GetSoftDeleteColumn returns the column marking soft deleted entities, or blank if entities are not soft deleted.
+	GetSoftDeleteColumn() string
+	// GetAuditColumns returns the audit columns of entities, or nil if entities are not audited.
+	GetAuditColumns() *AuditColumns
+	// ApplyAuditValues sets the audit fields of the internal entity to the current time and the actor of the context.
+	// The created fields are only set if created is true. Does nothing if entities are not audited.
+	ApplyAuditValues(ctx context.Context, entry I, created bool) error
+	// GetActor returns the actor making changes in the context, as recorded in the audit columns and history.
+	GetActor(ctx context.Context) string
+	// GetInstrumentation returns the instrumentation of the operations of the repository.
+	GetInstrumentation() Instrumentation
+	// ApplyContextScopeQueryBuilder applies context-based query modifications to the database query. Soft deleted
+	// entities are excluded, unless the context is marked with data.WithDeleted. Reads are routed to a replica, if
+	// any, as by ReadFromReplica.
 	ApplyContextScopeQueryBuilder(ctx context.Context, db *gorm.DB, fetchType data.FetchType) *gorm.DB
 	// ApplyContextScopeValueInjector applies context-based value injections to the provided internal entity.
 	ApplyContextScopeValueInjector(ctx context.Context, entry I, fetchType data.FetchType)
 	// DoFind performs a database query based on a delegate and returns a single mapped external entity or an error.
+	// Returns data.EntityNotFound if the query finds no entity.
 	DoFind(ctx context.Context, delegate func(db *gorm.DB, entry I) (*gorm.DB, error)) (E, error)
 	// DoList executes a query based on criteria and parameters, returning a paginated list of external entities or an error.
 	DoList(ctx context.Context, criteriaBuilder func(tx *gorm.DB) *gorm.DB, params data.ListParams) (*data.List[E], error)
+	// DoListScored executes a query based on criteria, which also provide the expression of a relevance score, and
+	// parameters, returning a paginated list of external entities with their score or an error.
+	DoListScored(ctx context.Context, criteriaBuilder func(tx *gorm.DB) (*gorm.DB, clause.Expr), params data.ListParams) (*data.List[*data.SearchResult[E]], error)
+	// DoCount returns the number of entities matching the criteria and the selector of the parameters, across all
+	// pages. Paging and sorting are ignored.
+	DoCount(ctx context.Context, criteriaBuilder func(tx *gorm.DB) *gorm.DB, params data.ListParams) (int64, error)
 	// ToInternal converts an external entity representation into its internal counterpart.
 	ToInternal(in E) I
 	// FromInternal converts an internal entity representation back into its external form.
@@ -34,24 +56,44 @@ type Template[E any] interface {
 }
 
 type templateImpl[E any, I any] struct {
-	contextScope ContextScopeFactory
-	table        string
-	toInternal   func(in E) I
-	fromInternal func(in I) E
+	contextScope     ContextScopeFactory
+	table            string
+	keyColumns       []string
+	labelsColumn     string
+	sortableFields   []string
+	softDeleteColumn string
+	auditColumns     *AuditColumns
+	actorProvider    ActorProvider
+	instrumentation  Instrumentation
+	toInternal       func(in E) I
+	fromInternal     func(in I) E
 }
 
 // TemplateParams defines parameters required for creating templates with optional context scope and table name.
 type TemplateParams[E any, I any] struct {
 	ContextScope ContextScopeFactory
 	Table        string
+	// KeyColumns are the columns of the key, used for ordering and pagination. Defaults to the primary key of the model.
+	KeyColumns []string
+	// LabelsColumn is the JSON column storing the labels of the entity. When set, label selectors are applied in SQL.
+	LabelsColumn string
+	// SortableFields are the columns, in addition to the key columns, which may be used in sort criteria.
+	SortableFields []string
+	// SoftDeleteColumn is the nullable timestamp column marking soft deleted entities. When set, deletes mark the
+	// entity as deleted and queries exclude soft deleted entities.
+	SoftDeleteColumn string
 }
 
 // NewTemplate initializes and returns a Template instance for mapping entities with the specified parameters.
 func NewTemplate[E any](params TemplateParams[E, E]) Template[E] {
 
 	return NewMappingTemplate[E, E](MappingTemplateParams[E, E]{
-		ContextScope: params.ContextScope,
-		Table:        params.Table,
+		ContextScope:     params.ContextScope,
+		Table:            params.Table,
+		KeyColumns:       params.KeyColumns,
+		LabelsColumn:     params.LabelsColumn,
+		SortableFields:   params.SortableFields,
+		SoftDeleteColumn: params.SoftDeleteColumn,
 		ToInternal: func(in E) E {
 			return in
 		},
@@ -66,18 +108,52 @@ func NewTemplate[E any](params TemplateParams[E, E]) Template[E] {
 type MappingTemplateParams[E any, I any] struct {
 	ContextScope ContextScopeFactory
 	Table        string
-	ToInternal   func(in E) I
-	FromInternal func(in I) E
+	// KeyColumns are the columns of the key, used for ordering and pagination. Defaults to the primary key of the model.
+	KeyColumns []string
+	// LabelsColumn is the JSON column storing the labels of the entity. When set, label selectors are applied in SQL.
+	LabelsColumn string
+	// SortableFields are the columns, in addition to the key columns, which may be used in sort criteria.
+	SortableFields []string
+	// SoftDeleteColumn is the nullable timestamp column marking soft deleted entities. When set, deletes mark the
+	// entity as deleted and queries exclude soft deleted entities.
+	SoftDeleteColumn string
+	// AuditColumns are the columns recording when and by whom entities are created and last updated. When set, they
+	// are populated on create and update.
+	AuditColumns *AuditColumns
+	// ActorProvider returns the actor recorded in the audit columns. Defaults to ContextActorProvider.
+	ActorProvider ActorProvider
+	// Instrumentation observes the operations of the repository, such as to emit spans and metrics. Defaults to
+	// NoopInstrumentation.
+	Instrumentation Instrumentation
+	ToInternal      func(in E) I
+	FromInternal    func(in I) E
 }
 
 // NewMappingTemplate initializes and returns a new MappingTemplate using the provided MappingTemplateParams.
 func NewMappingTemplate[E any, I any](params MappingTemplateParams[E, I]) MappingTemplate[E, I] {
 
+	actorProvider := params.ActorProvider
+	if actorProvider == nil {
+		actorProvider = ContextActorProvider
+	}
+
+	instrumentation := params.Instrumentation
+	if instrumentation == nil {
+		instrumentation = NoopInstrumentation{}
+	}
+
 	return &templateImpl[E, I]{
-		contextScope: params.ContextScope,
-		table:        params.Table,
-		toInternal:   params.ToInternal,
-		fromInternal: params.FromInternal,
+		contextScope:     params.ContextScope,
+		table:            params.Table,
+		keyColumns:       params.KeyColumns,
+		labelsColumn:     params.LabelsColumn,
+		sortableFields:   params.SortableFields,
+		softDeleteColumn: params.SoftDeleteColumn,
+		auditColumns:     params.AuditColumns,
+		actorProvider:    actorProvider,
+		instrumentation:  instrumentation,
+		toInternal:       params.ToInternal,
+		fromInternal:     params.FromInternal,
 	}
 }
 
@@ -96,14 +172,55 @@ func (c *templateImpl[E, I]) GetTable() string {
 	return c.table
 }
 
-// ApplyContextScopeQueryBuilder applies context-specific query scopes to the provided Gorm DB instance based on fetch type.
+// GetSoftDeleteColumn returns the column marking soft deleted entities, or blank if entities are not soft deleted.
+func (c *templateImpl[E, I]) GetSoftDeleteColumn() string {
+	return c.softDeleteColumn
+}
+
+// GetAuditColumns returns the audit columns of entities, or nil if entities are not audited.
+func (c *templateImpl[E, I]) GetAuditColumns() *AuditColumns {
+	return c.auditColumns
+}
+
+// ApplyAuditValues sets the audit fields of the internal entity to the current time and the actor of the context.
+func (c *templateImpl[E, I]) ApplyAuditValues(ctx context.Context, entry I, created bool) error {
+
+	if c.auditColumns == nil {
+		return nil
+	}
+
+	sch, err := parseSchema(GetDB(ctx), entry)
+	if err != nil {
+		return err
+	}
+
+	return applyAuditValues(ctx, sch, c.auditColumns, entry, time.Now(), c.actorProvider(ctx), created)
+}
+
+// GetActor returns the actor making changes in the context, as recorded in the audit columns and history.
+func (c *templateImpl[E, I]) GetActor(ctx context.Context) string {
+	return c.actorProvider(ctx)
+}
+
+// GetInstrumentation returns the instrumentation of the template.
+func (c *templateImpl[E, I]) GetInstrumentation() Instrumentation {
+	return c.instrumentation
+}
+
+// ApplyContextScopeQueryBuilder applies context-specific query scopes to the provided Gorm DB instance based on fetch
+// type, excludes soft deleted entities unless the context includes them, and routes reads to replicas.
 func (c *templateImpl[E, I]) ApplyContextScopeQueryBuilder(ctx context.Context, db *gorm.DB, fetchType data.FetchType) *gorm.DB {
 
 	var scopes []func(*gorm.DB) *gorm.DB
 	if c.contextScope != nil {
 		scopes = append(scopes, c.contextScope(ctx, c.table, fetchType).QueryModifier)
 	}
-	return db.Scopes(scopes...)
+	if c.softDeleteColumn != "" && !data.IsWithDeleted(ctx) {
+		scopes = append(scopes, func(tx *gorm.DB) *gorm.DB {
+			return tx.Where(fmt.Sprintf("%s IS NULL", qualifyColumn(c.table, c.softDeleteColumn)))
+		})
+	}
+	return ReadFromReplica(ctx, db.Scopes(scopes...), fetchType)
 }
 
 // ApplyContextScopeValueInjector injects context-specific values into the provided entry based on fetch type and scope configuration.
@@ -115,8 +232,24 @@ func (c *templateImpl[E, I]) ApplyContextScopeValueInjector(ctx context.Context,
 }
 
 // DoFind performs a database query using the provided delegate function and processes the result based on row count.
+// Returns data.EntityNotFound if no row is found.
 func (c *templateImpl[E, I]) DoFind(ctx context.Context, delegate func(db *gorm.DB, entry I) (*gorm.DB, error)) (E, error) {
 
+	ctx, end := instrument(ctx, c.instrumentation, &Operation{
+		Name:      OperationDoFind,
+		Table:     c.table,
+		FetchType: data.FetchTypeDetail,
+	})
+
+	res, rows, err := c.doFind(ctx, delegate)
+	end(rows, err)
+
+	return res, err
+}
+
+// doFind performs the query of DoFind, also returning the number of entities found.
+func (c *templateImpl[E, I]) doFind(ctx context.Context, delegate func(db *gorm.DB, entry I) (*gorm.DB, error)) (E, int64, error) {
+
 	tx := GetDB(ctx).Table(c.table)
 	tx = c.ApplyContextScopeQueryBuilder(ctx, tx, data.FetchTypeDetail)
 
@@ -130,142 +263,238 @@ func (c *templateImpl[E, I]) DoFind(ctx context.Context, delegate func(db *gorm.
 
 	switch {
 	case err != nil:
-		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
-			return reflect.NilInterface[E](), nil
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return reflect.NilInterface[E](), 0, data.EntityNotFound{}
 		}
-		return reflect.NilInterface[E](), err
+		return reflect.NilInterface[E](), 0, TranslateError(err)
 	case tx.RowsAffected == 0:
-		return reflect.NilInterface[E](), nil
+		return reflect.NilInterface[E](), 0, data.EntityNotFound{}
 	case tx.RowsAffected == 1:
-		return c.fromInternal(e), nil
+		return c.fromInternal(e), 1, nil
 	default:
 		// Rows are more than 1
-		return reflect.NilInterface[E](), fmt.Errorf("expected 1 record, but was %d", tx.RowsAffected)
+		return reflect.NilInterface[E](), tx.RowsAffected, fmt.Errorf("expected 1 record, but was %d", tx.RowsAffected)
 	}
 }
 
-// DoList retrieves a list of external entities based on the provided criteria and list parameters.
+// DoList retrieves a page of external entities based on the provided criteria and list parameters. Entities are
+// ordered by the sort criteria and then by the key columns, and the returned NextPageToken resumes the listing after
+// the last returned entity.
+// Label selectors are applied in the query when a labels column is configured, otherwise they are applied in memory.
 func (c *templateImpl[E, I]) DoList(ctx context.Context,
 	criteriaBuilder func(tx *gorm.DB) *gorm.DB,
-	params data.ListParams) (*data.List[E], error) {
+	params data.ListParams) (res *data.List[E], err error) {
+
+	ctx, end := instrument(ctx, c.instrumentation, &Operation{
+		Name:      OperationDoList,
+		Table:     c.table,
+		FetchType: data.FetchTypeList,
+	})
+	defer func() {
+		if res != nil {
+			end(int64(len(res.List)), err)
+		} else {
+			end(0, err)
+		}
+	}()
+
+	got, err := c.DoListScored(ctx, func(tx *gorm.DB) (*gorm.DB, clause.Expr) {
+		if criteriaBuilder != nil {
+			tx = criteriaBuilder(tx)
+		}
+		return tx, clause.Expr{}
+	}, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]E, len(got.List))
+
+	for i, r := range got.List {
+		result[i] = r.Entity
+	}
+
+	return &data.List[E]{
+		NextPageToken: got.NextPageToken,
+		List:          result,
+	}, nil
+}
+
+// DoCount returns the number of entities matching the criteria and the selector of the parameters, across all pages.
+// Rows are only loaded when the selector has requirements which cannot be applied in the query.
+func (c *templateImpl[E, I]) DoCount(ctx context.Context,
+	criteriaBuilder func(tx *gorm.DB) *gorm.DB,
+	params data.ListParams) (int64, error) {
 
 	tx := GetDB(ctx).Table(c.table)
 
 	tx = c.ApplyContextScopeQueryBuilder(ctx, tx, data.FetchTypeList)
 
-	if criteriaBuilder == nil {
-		criteriaBuilder = func(tx *gorm.DB) *gorm.DB {
-			return tx
-		}
+	if criteriaBuilder != nil {
+		tx = criteriaBuilder(tx)
 	}
 
-	/*
+	selector := params.Selector
 
-			// TODO - implement
+	if c.labelsColumn != "" {
+		tx, selector = ApplyLabelSelector(tx, qualifyColumn(c.table, c.labelsColumn), selector)
+	}
 
-			if pageParams == nil {
-				pageParams = &repository_inner.PageParams{
-					First: "",
-					Last:  "",
-					Count: 0,
-				}
-			}
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+
+	if selector == nil {
 
+		var count int64
 
-		if pageParams.Count == 0 {
-			// We default to a sensible number
-			pageParams.Count = 100
+		if err := tx.Count(&count).Error; err != nil {
+			return 0, TranslateError(err)
 		}
 
+		return count, nil
+	}
 
+	// Fallback for selectors which could not be applied in the query
+	var rows []I
 
-			// TODO - fix
+	if err := tx.Select(fmt.Sprintf("%s.*", c.table)).Find(&rows).Error; err != nil {
+		return 0, TranslateError(err)
+	}
 
-			for _, sc := range sortCriteria {
-				f := ToSnakeCase(sc.Field)
-				s := f
-				if sc.Reverse != (pageParams.First != "") {
-					s = s + " DESC"
-				}
-				tx = tx.Order(s)
-				if pageParams.First != "" {
-					tx = tx.Where(f+" < ?", pageParams.First)
-				} else if pageParams.Last != "" {
-					tx = tx.Where(f+" > ?", pageParams.Last)
-				}
-			}
+	entities := make([]E, len(rows))
+	for i, r := range rows {
+		entities[i] = c.fromInternal(r)
+	}
 
-	*/
+	matched, err := data.FilterByLabels(selector, entities)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(matched)), nil
+}
 
-	// tx = tx.Limit(pageParams.Count + 1)
-	tx = tx.Limit(100)
+// scoredRow is a row of the internal entity together with its relevance score.
+type scoredRow[I any] struct {
+	Row   I       `gorm:"embedded"`
+	Score float64 `gorm:"column:search_score"`
+}
 
-	tx = criteriaBuilder(tx)
+// DoListScored retrieves a page of external entities with their relevance score. The criteria builder returns the
+// expression of the score, which is blank when the query is not scored. Without sort criteria, scored entities are
+// ordered by descending score, and then by the key columns.
+func (c *templateImpl[E, I]) DoListScored(ctx context.Context,
+	criteriaBuilder func(tx *gorm.DB) (*gorm.DB, clause.Expr),
+	params data.ListParams) (*data.List[*data.SearchResult[E]], error) {
 
-	if tx.Error != nil {
-		return nil, tx.Error
+	tx := GetDB(ctx).Table(c.table)
+
+	tx = c.ApplyContextScopeQueryBuilder(ctx, tx, data.FetchTypeList)
+
+	var score clause.Expr
+
+	if criteriaBuilder != nil {
+		tx, score = criteriaBuilder(tx)
 	}
 
-	var results []I
+	selector := params.Selector
 
-	tx.Find(&results)
+	if c.labelsColumn != "" {
+		tx, selector = ApplyLabelSelector(tx, qualifyColumn(c.table, c.labelsColumn), selector)
+	}
 
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
 
-	/*
-		TODO - Fix
-		overflow := pageParams.Count != 0 && len(results) == pageParams.Count+1
+	sch, err := parseSchema(tx, reflect.ZeroInterface[I]())
+	if err != nil {
+		return nil, err
+	}
 
-		pageInfo := &repository_inner.PageInfo{}
+	keyColumns := c.keyColumns
+	if len(keyColumns) == 0 {
+		keyColumns = sch.PrimaryFieldDBNames
+	}
+	if len(keyColumns) == 0 {
+		return nil, fmt.Errorf("no key columns for table %s", c.table)
+	}
 
-		if pageParams.First != "" {
-			reverseAny(results)
-			pageInfo.HasNextPage = true
-			if overflow {
-				pageInfo.HasPreviousPage = true
-			}
-		} else if pageParams.Last != "" {
-			pageInfo.HasPreviousPage = true
-			if overflow {
-				pageInfo.HasNextPage = true
-			}
-		} else {
-			if overflow {
-				pageInfo.HasNextPage = true
-			}
-		}
+	terms, err := sortOrderTerms(c.table, sch, c.sortableFields, keyColumns, params.Sort)
+	if err != nil {
+		return nil, err
+	}
 
-		if overflow {
-			if pageParams.First != "" {
-				results = results[1:]
-			} else {
-				results = results[:len(results)-1]
-			}
+	// Columns are selected explicitly since the score is not a column of the table
+	if score.SQL == "" {
+		tx = tx.Select(fmt.Sprintf("%s.*", c.table))
+	} else {
+
+		tx = tx.Select(fmt.Sprintf("%s.*, %s AS %s", c.table, score.SQL, searchScoreColumn), score.Vars...)
+
+		if len(params.Sort) == 0 {
+			terms = append([]orderTerm{{
+				Column: searchScoreColumn,
+				Expr:   score.SQL,
+				Args:   score.Vars,
+				Desc:   true,
+				Type:   searchScoreType,
+			}}, terms...)
 		}
+	}
 
-		if len(results) > 0 {
-			pageInfo.StartCursor = makeCursor2(sortCriteria, results[0])
-			pageInfo.EndCursor = makeCursor2(sortCriteria, results[len(results)-1])
-		}
-	*/
+	p, err := newPage(params.PageParams, terms)
+	if err != nil {
+		return nil, err
+	}
+
+	tx = p.apply(tx)
 
-	externalResults := make([]E, len(results))
+	var results []*scoredRow[I]
 
-	for i, in := range results {
-		externalResults[i] = c.fromInternal(in)
+	tx.Find(&results)
+
+	if tx.Error != nil {
+		return nil, TranslateError(tx.Error)
 	}
 
-	if params.Selector != nil {
-		var err error
-		externalResults, err = data.FilterByLabels(params.Selector, externalResults)
-		if err != nil {
-			return nil, err
+	rowSch, err := parseSchema(tx, &scoredRow[I]{})
+	if err != nil {
+		return nil, err
+	}
+
+	results, token, err := completePage(ctx, rowSch, p, results)
+	if err != nil {
+		return nil, err
+	}
+
+	externalResults := make([]*data.SearchResult[E], 0, len(results))
+
+	for _, r := range results {
+
+		e := c.fromInternal(r.Row)
+
+		// Fallback for selectors which could not be applied in the query
+		if selector != nil {
+			matched, err := data.FilterByLabels(selector, []E{e})
+			if err != nil {
+				return nil, err
+			}
+			if len(matched) == 0 {
+				continue
+			}
 		}
+
+		externalResults = append(externalResults, &data.SearchResult[E]{
+			Score:  float32(r.Score),
+			Entity: e,
+		})
 	}
 
-	return &data.List[E]{
-		List: externalResults,
+	return &data.List[*data.SearchResult[E]]{
+		NextPageToken: token,
+		List:          externalResults,
 	}, nil
 }
@@ -0,0 +1,63 @@
+package gorm_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/activatedio/datainfra/pkg/data"
+	"github.com/activatedio/datainfra/pkg/data/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gorm2 "gorm.io/gorm"
+)
+
+func TestDoFind_NotFound(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	db, err := gorm.NewDB(&gorm.Config{
+		Dialect: gorm.DialectSqlite,
+		Name:    filepath.Join(t.TempDir(), "find"),
+	})
+	r.NoError(err)
+
+	r.NoError(db.Exec("CREATE TABLE paged_events (id INTEGER PRIMARY KEY, occurred_at TIMESTAMP NOT NULL)").Error)
+	r.NoError(db.Create(&pagedEvent{ID: 1, OccurredAt: time.Now()}).Error)
+
+	unit := gorm.NewTemplate[*pagedEvent](gorm.TemplateParams[*pagedEvent, *pagedEvent]{
+		Table: "paged_events",
+	})
+
+	ctx := gorm.WithDB(context.Background(), db)
+
+	cases := map[string]func(tx *gorm2.DB, e *pagedEvent) *gorm2.DB{
+		// First fails with gorm.ErrRecordNotFound
+		"first": func(tx *gorm2.DB, e *pagedEvent) *gorm2.DB {
+			return tx.Where("id = ?", 2).First(e)
+		},
+		// Find affects no rows
+		"find": func(tx *gorm2.DB, e *pagedEvent) *gorm2.DB {
+			return tx.Where("id = ?", 2).Find(e)
+		},
+	}
+
+	for name, query := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := unit.DoFind(ctx, func(tx *gorm2.DB, e *pagedEvent) (*gorm2.DB, error) {
+				return query(tx, e), nil
+			})
+			a.True(errors.Is(err, data.EntityNotFound{}))
+			a.True(errors.Is(err, data.ErrNotFound))
+			a.Nil(got)
+		})
+	}
+
+	got, err := unit.DoFind(ctx, func(tx *gorm2.DB, e *pagedEvent) (*gorm2.DB, error) {
+		return tx.Where("id = ?", 1).First(e), nil
+	})
+	r.NoError(err)
+	a.Equal(int64(1), got.ID)
+}
@@ -100,26 +100,24 @@ type GormTestingConfigResult struct {
 }
 
 // NewStaticGormTestingConfig creates a static GORM testing configuration function using the provided configs and migrator data.
-func NewStaticGormTestingConfig(ownerConfig, appConfig *gorm2.Config, migratorData []gormmigrate.MigratorData) func() GormTestingConfigResult {
-	return func() GormTestingConfigResult {
+// Migrations are run with the owner config on the database of the app config, so the owner config cannot have a DSN.
+func NewStaticGormTestingConfig(ownerConfig, appConfig *gorm2.Config, migratorData []gormmigrate.MigratorData) func() (GormTestingConfigResult, error) {
+	return func() (GormTestingConfigResult, error) {
+
+		migratorConfig, err := ownerConfig.WithName(appConfig.Name)
+		if err != nil {
+			return GormTestingConfigResult{}, err
+		}
+
 		return GormTestingConfigResult{
 			GormConfig: appConfig,
 			SetupGormConfig: &gormsetup.OwnerGormConfig{
 				Config: *ownerConfig,
 			},
 			MigratorGormConfig: &gormmigrate.MigratorGormConfig{
-				GormConfig: gorm2.Config{
-					Dialect:                  ownerConfig.Dialect,
-					EnableDefaultTransaction: ownerConfig.EnableDefaultTransaction,
-					EnableSQLLogging:         ownerConfig.EnableSQLLogging,
-					Host:                     ownerConfig.Host,
-					Port:                     ownerConfig.Port,
-					Username:                 ownerConfig.Username,
-					Password:                 ownerConfig.Password,
-					Name:                     appConfig.Name,
-				},
+				GormConfig: *migratorConfig,
 			},
 			MigratorData: migratorData,
-		}
+		}, nil
 	}
 }
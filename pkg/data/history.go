@@ -0,0 +1,39 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// HistoryOperation is the kind of change recorded in the history of an entity.
+type HistoryOperation string
+
+const (
+	// HistoryOperationCreate records the creation of an entity
+	HistoryOperationCreate HistoryOperation = "CREATE"
+	// HistoryOperationUpdate records the update of an entity
+	HistoryOperationUpdate HistoryOperation = "UPDATE"
+	// HistoryOperationDelete records the deletion of an entity
+	HistoryOperationDelete HistoryOperation = "DELETE"
+	// HistoryOperationRestore records the restore of a soft deleted entity
+	HistoryOperationRestore HistoryOperation = "RESTORE"
+)
+
+// HistoryEntry is a recorded change of an entity, with JSON snapshots of the stored entity before and after the change.
+type HistoryEntry struct {
+	Operation HistoryOperation
+	// Actor is the actor of the context the change was made in, or blank if none was set.
+	Actor     string
+	ChangedAt time.Time
+	// Before is the snapshot before the change, or nil if the entity was created.
+	Before json.RawMessage
+	// After is the snapshot after the change, or nil if the entity was removed.
+	After json.RawMessage
+}
+
+// HistoryTemplate defines reading the recorded changes of entities with key of type K.
+type HistoryTemplate[K comparable] interface {
+	// ListHistory lists the changes of the entity with the key, oldest first unless sorted otherwise.
+	ListHistory(ctx context.Context, key K, params ListParams) (*List[*HistoryEntry], error)
+}
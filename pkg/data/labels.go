@@ -1,10 +1,15 @@
 package data
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
 	"k8s.io/apimachinery/pkg/labels"
 )
 
 // Labels is a type alias for labels.Set used to represent a set of label key-value pairs.
+// Labels are stored as a JSON object, allowing them to be mapped to a JSON column.
 type Labels labels.Set
 
 // EmptyLabelsSet returns an empty Labels set
@@ -12,6 +17,51 @@ func EmptyLabelsSet() Labels {
 	return Labels{}
 }
 
+// Value returns the JSON representation of the labels for storage. Nil labels are stored as an empty object.
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads labels from their stored JSON representation.
+func (l *Labels) Scan(src any) error {
+
+	var b []byte
+
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type %T for labels", src)
+	}
+
+	res := Labels{}
+
+	if err := json.Unmarshal(b, &res); err != nil {
+		return err
+	}
+
+	*l = res
+	return nil
+}
+
+// ValidateLabels validates label keys and values, returning an error describing the first invalid entry.
+func ValidateLabels(l Labels) error {
+	_, err := labels.ValidatedSelectorFromSet(labels.Set(l))
+	return err
+}
+
 // WithLabels represents an interface for objects that can return a Labels type
 type WithLabels interface {
 	// GetLabels returns the set of label key-value pairs associated with the implementing object.
@@ -0,0 +1,29 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Event is a change of an entity, appended to the outbox within the transaction of the change and published once the
+// transaction is committed.
+type Event struct {
+	// ID identifies the event, increasing in the order events are appended. Publishers may use it to drop duplicates.
+	ID         int64
+	EntityType string
+	// EntityKey is the JSON encoding of the key of the entity.
+	EntityKey string
+	Operation HistoryOperation
+	// Payload is the JSON snapshot of the entity after the change, or before it for deletes.
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
+
+// Publisher publishes the events of the outbox, such as to a message broker. Events are delivered at least once, and
+// the events of an entity are delivered in order.
+type Publisher interface {
+	// Publish publishes the event, returning an error if it could not be published, in which case it is retried along
+	// with the later events of the same entity.
+	Publish(ctx context.Context, event *Event) error
+}
@@ -0,0 +1,17 @@
+package data
+
+import "context"
+
+type primaryContextKey struct{}
+
+// WithPrimary returns a new context in which reads go to the primary database rather than its replicas, such as to
+// read the writes made earlier in the context.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// IsWithPrimary returns true if reads in the context go to the primary database.
+func IsWithPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryContextKey{}).(bool)
+	return v
+}
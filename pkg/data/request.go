@@ -0,0 +1,17 @@
+package data
+
+import "context"
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a new context carrying the ID of the request or trace, which is logged with the queries made
+// in the context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// GetRequestID returns the ID of the request or trace in the context, or blank if there is none.
+func GetRequestID(ctx context.Context) string {
+	v, _ := ctx.Value(requestIDContextKey{}).(string)
+	return v
+}
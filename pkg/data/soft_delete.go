@@ -0,0 +1,26 @@
+package data
+
+import "context"
+
+type softDeleteContextKey struct{}
+
+// SoftDeleteTemplate defines operations on entities which are soft deleted, where deletion marks the entity as
+// deleted rather than removing it.
+type SoftDeleteTemplate[K comparable] interface {
+	// Restore clears the deletion of a soft deleted entity. Returns EntityNotFound if no soft deleted entity with
+	// the key exists in the current scope.
+	Restore(ctx context.Context, key K) error
+	// Purge permanently removes an entity, whether or not it is soft deleted.
+	Purge(ctx context.Context, key K) error
+}
+
+// WithDeleted returns a new context in which queries include soft deleted entities.
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, softDeleteContextKey{}, true)
+}
+
+// IsWithDeleted returns true if queries in the context include soft deleted entities.
+func IsWithDeleted(ctx context.Context) bool {
+	v, _ := ctx.Value(softDeleteContextKey{}).(bool)
+	return v
+}
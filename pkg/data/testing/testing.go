@@ -65,6 +65,9 @@ type CrudTestFixture[E any, K comparable] struct {
 	AssertAfterCreate  func(t *testing.T, e E)
 	ModifyBeforeUpdate func(e E)
 	AssertAfterUpdate  func(t *testing.T, e E)
+	// OtherScopeContext returns a context of another scope, in which entities created in the test context are not
+	// visible. When set, reads and writes from the other scope are asserted to not affect the entity.
+	OtherScopeContext func(ctx context.Context) context.Context
 }
 
 // DoTestCrud performs a comprehensive CRUD test for a generic repository using provided test fixtures.
@@ -85,6 +88,13 @@ func DoTestCrud[E any, K comparable](t *testing.T,
 
 		require.NoError(t, err)
 		assert.Len(t, list.List, sa.ExpectedCount, sa.Expression)
+
+		count, err := unit.Count(ctx, data.ListParams{
+			Selector: l,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(sa.ExpectedCount), count, sa.Expression)
 	}
 
 	if fixture.ListAssertion != nil {
@@ -100,18 +110,33 @@ func DoTestCrud[E any, K comparable](t *testing.T,
 		for _, v := range list.List {
 			la.AssertListEntry(t, v)
 		}
+
+		count, err := unit.Count(ctx, data.ListParams{})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(la.ExpectedCount), count)
 	}
 
 	got, err := unit.FindByKey(ctx, fixture.KeyMissing)
 
-	require.NoError(t, err)
+	assert.True(t, errors.Is(err, data.EntityNotFound{}))
 	assert.Nil(t, got)
 
+	exists, err := unit.ExistsByKey(ctx, fixture.KeyMissing)
+
+	require.NoError(t, err)
+	assert.False(t, exists)
+
 	got, err = unit.FindByKey(ctx, fixture.KeyExists)
 
 	require.NoError(t, err)
 	assert.NotNil(t, got)
 
+	exists, err = unit.ExistsByKey(ctx, fixture.KeyExists)
+
+	require.NoError(t, err)
+	assert.True(t, exists)
+
 	fixture.AssertDetailEntry(t, got)
 
 	// Create with bad labels
@@ -140,8 +165,41 @@ func DoTestCrud[E any, K comparable](t *testing.T,
 
 	fixture.AssertAfterCreate(t, got2)
 
+	if fixture.OtherScopeContext != nil {
+
+		otherCtx := fixture.OtherScopeContext(ctx)
+
+		got3, err := unit.FindByKey(otherCtx, key)
+		assert.True(t, errors.Is(err, data.EntityNotFound{}))
+		assert.Nil(t, got3)
+
+		exists, err = unit.ExistsByKey(otherCtx, key)
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		err = unit.Update(otherCtx, got)
+		assert.True(t, errors.Is(err, data.EntityNotFound{}))
+
+		err = unit.Delete(otherCtx, key)
+		assert.True(t, errors.Is(err, data.EntityNotFound{}))
+
+		err = unit.DeleteEntity(otherCtx, got)
+		assert.True(t, errors.Is(err, data.EntityNotFound{}))
+
+		got3, err = unit.FindByKey(ctx, key)
+		require.NoError(t, err)
+		require.NotNil(t, got3)
+		fixture.AssertAfterCreate(t, got3)
+	}
+
 	if fixture.AssertAfterUpdate != nil && fixture.ModifyBeforeUpdate != nil {
 
+		missing := fixture.NewEntity()
+		fixture.ModifyBeforeCreate(missing)
+
+		err = unit.Update(ctx, missing)
+		assert.True(t, errors.Is(err, data.EntityNotFound{}))
+
 		fixture.ModifyBeforeUpdate(got)
 
 		err = unit.Update(ctx, got)
@@ -167,9 +225,14 @@ func DoTestCrud[E any, K comparable](t *testing.T,
 
 	got3, err := unit.FindByKey(ctx, key)
 
-	require.NoError(t, err)
+	assert.True(t, errors.Is(err, data.EntityNotFound{}))
 	assert.Nil(t, got3)
 
+	exists, err = unit.ExistsByKey(ctx, key)
+
+	require.NoError(t, err)
+	assert.False(t, exists)
+
 }
 
 // SetBadLabels modifies the "Labels" field of the provided struct to set intentionally malformed key-value pairs.
@@ -240,7 +303,7 @@ func DoTestSearch[E any, T data.SearchTemplate[E]](t *testing.T, ctx context.Con
 
 			_ctx, preds := v2.Arrange(ctx)
 
-			got, err := unit.Search(_ctx, preds, nil)
+			got, err := unit.Search(_ctx, preds, data.ListParams{})
 			v2.Assert(got, err)
 		})
 	}
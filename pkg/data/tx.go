@@ -0,0 +1,55 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrNestedTxOptions indicates a nested transaction was requested with options which differ from those of the outer
+// transaction.
+var ErrNestedTxOptions = errors.New("nested transaction options differ from the outer transaction")
+
+// TxOptions represents the configuration options of a transaction.
+type TxOptions struct {
+	// ReadOnly indicates the transaction does not modify data.
+	ReadOnly bool
+	// Isolation is the isolation level of the transaction. The default level of the database is used when not set.
+	Isolation sql.IsolationLevel
+}
+
+// TxOption defines a function type for configuring TxOptions when starting a transaction.
+type TxOption func(options *TxOptions)
+
+// WithReadOnly configures the transaction as read only.
+func WithReadOnly() TxOption {
+	return func(options *TxOptions) {
+		options.ReadOnly = true
+	}
+}
+
+// WithIsolation configures the isolation level of the transaction.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(options *TxOptions) {
+		options.Isolation = level
+	}
+}
+
+// NewTxOptions creates TxOptions with the provided options applied.
+func NewTxOptions(opts ...TxOption) *TxOptions {
+	o := &TxOptions{}
+	for _, applyOpt := range opts {
+		applyOpt(o)
+	}
+	return o
+}
+
+// TxManager runs operations within a transaction carried by the context.
+type TxManager interface {
+	// InTx runs fn within a transaction. The context passed to fn carries the transaction, so that all templates
+	// and repositories called with it take part in the transaction. The transaction is committed if fn returns nil
+	// and rolled back otherwise. When ctx already carries a transaction, a savepoint is used instead. Options
+	// given for a nested transaction must match those of the outer transaction, otherwise ErrNestedTxOptions is
+	// returned.
+	InTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+}
@@ -87,10 +87,12 @@ type SearchPredicate struct {
 
 // SearchTemplate defines an interface for executing search operations and retrieving search predicate information.
 type SearchTemplate[E any] interface {
-	// Search performs a search operation with the given criteria and paging parameters.
-	Search(ctx context.Context, criteria []*SearchPredicate, pageParams *PageParams) (*List[*SearchResult[E]], error)
+	// Search performs a search operation with the given criteria and list parameters.
+	Search(ctx context.Context, criteria []*SearchPredicate, params ListParams) (*List[*SearchResult[E]], error)
 	// GetSearchPredicates returns a list of available search predicates for filtering results.
 	GetSearchPredicates(ctx context.Context) ([]*SearchPredicateDescriptor, error)
+	// CountSearch returns the number of results of the search across all pages. Paging and sorting are ignored.
+	CountSearch(ctx context.Context, criteria []*SearchPredicate, params ListParams) (int64, error)
 }
 
 // None represents a type used as a placeholder or marker when no meaningful value or identifier is required.
@@ -125,19 +127,40 @@ type ScopeTemplate[S Scope] interface {
 
 // FindByKeyTemplate provides functionality to locate entities by their key and check their existence in a data store.
 type FindByKeyTemplate[E any, K comparable] interface {
+	// FindByKey returns the entity with the key, or EntityNotFound if it does not exist or is not visible.
 	FindByKey(ctx context.Context, key K) (E, error)
 	ExistsByKey(ctx context.Context, key K) (bool, error)
 }
 
-// ListParams specifies parameters for filtering and paginating results in a list operation.
+// SortDirection represents the direction in which a field is sorted.
+type SortDirection int
+
+const (
+	// SortDirectionAscending sorts from the lowest to the highest value.
+	SortDirectionAscending SortDirection = iota
+	// SortDirectionDescending sorts from the highest to the lowest value.
+	SortDirectionDescending
+)
+
+// SortCriterion represents the sorting of results by a single field.
+type SortCriterion struct {
+	Field     string
+	Direction SortDirection
+}
+
+// ListParams specifies parameters for filtering, sorting and paginating results in a list operation.
 type ListParams struct {
 	PageParams *PageParams
 	Selector   labels.Selector
+	// Sort specifies the ordering of results. Fields must be sortable for the entity.
+	Sort []SortCriterion
 }
 
 // ListAllTemplate defines an interface for listing all entities of type E, with support for context and parameters.
 type ListAllTemplate[E any] interface {
 	ListAll(ctx context.Context, params ListParams) (*List[E], error)
+	// Count returns the number of entities across all pages. Paging and sorting are ignored.
+	Count(ctx context.Context, params ListParams) (int64, error)
 }
 
 // CrudTemplate defines a generic CRUD interface for managing entities of type E with key of type K in a data store.
@@ -150,6 +173,36 @@ type CrudTemplate[E any, K comparable] interface {
 	DeleteEntity(ctx context.Context, entity E) error
 }
 
+// UpsertTemplate defines the insertion of an entity of type E, or its update if it already exists.
+type UpsertTemplate[E any] interface {
+	// Upsert inserts the entity, or updates the existing entity with the same key. Returns true if the entity was
+	// inserted and false if it was updated.
+	Upsert(ctx context.Context, entity E) (bool, error)
+}
+
+// PatchTemplate defines the partial update of an entity of type E with key of type K.
+type PatchTemplate[E any, K comparable] interface {
+	// Patch updates only the fields in the field mask of the entity with the key, using the values of the provided
+	// entity. Returns InvalidFieldMask for fields which are not mapped or may not be updated, and EntityNotFound if no
+	// entity with the key exists in the current scope.
+	Patch(ctx context.Context, key K, fieldMask []string, entity E) error
+}
+
+// BatchTemplate defines operations on many entities of type E with key of type K, using as few round trips to the
+// data store as possible.
+type BatchTemplate[E any, K comparable] interface {
+	// FindByKeys returns the entities with the keys, in the order of the keys. The entry of a key which does not
+	// exist is the zero value of E.
+	FindByKeys(ctx context.Context, keys []K) ([]E, error)
+	// CreateMany inserts the entities and returns the result of each entity, in the order of the entities. The result
+	// of an entity is nil if it was created, or EntityAlreadyExists if an entity with the same key exists.
+	CreateMany(ctx context.Context, entities []E) ([]error, error)
+	// UpdateMany updates the entities. Either all or none of the entities are updated.
+	UpdateMany(ctx context.Context, entities []E) error
+	// DeleteByKeys deletes the entities with the keys. Keys which do not exist are ignored.
+	DeleteByKeys(ctx context.Context, keys []K) error
+}
+
 // FilterKeysTemplate is a generic interface for filtering keys of type K within a given context.
 // It verifies key existence based on the current scope to ensure data integrity and access control.
 type FilterKeysTemplate[K comparable] interface {
@@ -1,21 +1,15 @@
 package gorm
 
 import (
-	"fmt"
-	"io/fs"
-
 	datagorm "github.com/activatedio/datainfra/pkg/data/gorm"
 	"github.com/activatedio/datainfra/pkg/migrate"
 	"github.com/pressly/goose/v3"
 	"go.uber.org/fx"
 )
 
-// MigratorData represents a migration configuration including its name, file system, and relative path.
-type MigratorData struct {
-	Name string
-	FS   fs.FS
-	Path string
-}
+// MigratorData represents a migration configuration including its name, file system, and relative path. It is
+// declared in the data package, so that the HealthChecker checks the versions of the same migrations.
+type MigratorData = datagorm.MigratorData
 
 // migrator handles database migration processes using the provided configuration and migration data.
 type migrator struct {
@@ -38,12 +32,17 @@ func (m *migrator) Migrate() error {
 		return err
 	}
 
-	if err = goose.SetDialect(m.config.Dialect); err != nil {
+	dialect, err := datagorm.LookupDialect(m.config.Dialect)
+	if err != nil {
+		return err
+	}
+
+	if err = goose.SetDialect(dialect.GooseDialect()); err != nil {
 		return err
 	}
 
 	for _, d := range m.data {
-		goose.SetTableName(fmt.Sprintf("goose_migration_%s", d.Name))
+		goose.SetTableName(d.VersionTable())
 		goose.SetBaseFS(d.FS)
 		err = goose.Up(db, d.Path)
 		if err != nil {
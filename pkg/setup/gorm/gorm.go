@@ -2,6 +2,7 @@ package gorm
 
 import (
 	"fmt"
+	"strings"
 
 	datagorm "github.com/activatedio/datainfra/pkg/data/gorm"
 	"github.com/activatedio/datainfra/pkg/setup"
@@ -143,7 +144,7 @@ func (g *gormSetup) createUser() error {
 	if tx.Error != nil {
 		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 			log.Info().Msg("role not found, creating")
-			tx = g.db.Exec(fmt.Sprintf("CREATE USER %s WITH PASSWORD '%s'", g.appConfig.Username, g.appConfig.Password))
+			tx = g.db.Exec(fmt.Sprintf("CREATE USER %s WITH PASSWORD '%s'", g.appConfig.Username, strings.ReplaceAll(g.appConfig.Password, "'", "''")))
 			if tx.Error != nil {
 				return tx.Error
 			}
@@ -260,15 +261,13 @@ func (g *gormSetup) grantAllToDatabase() error {
 func (g *gormSetup) grantAllToSchema() error {
 
 	log.Info().Msg("granting schema permissions")
-	db, err := datagorm.NewDB(&datagorm.Config{
-		Dialect:          g.ownerConfig.Dialect,
-		Host:             g.ownerConfig.Host,
-		Port:             g.ownerConfig.Port,
-		Username:         g.ownerConfig.Username,
-		Password:         g.ownerConfig.Password,
-		Name:             g.appConfig.Name,
-		EnableSQLLogging: true,
-	})
+	config, err := g.ownerConfig.WithName(g.appConfig.Name)
+	if err != nil {
+		return err
+	}
+	config.EnableSQLLogging = true
+
+	db, err := datagorm.NewDB(config)
 
 	if err != nil {
 		return err
@@ -288,11 +287,5 @@ func (g *gormSetup) grantAllToSchema() error {
 		}
 	}
 
-	sDB, err := db.DB()
-
-	if err != nil {
-		return err
-	}
-
-	return sDB.Close()
+	return datagorm.CloseDB(db)
 }